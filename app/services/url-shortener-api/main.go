@@ -15,12 +15,22 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers"
+	v1handlers "github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers"
+	v2handlers "github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v2/handlers"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session/stores/sessiondb"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user/stores/userdb"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
 	v1 "github.com/MinaMamdouh2/URL-Shortener/business/web/v1"
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/debug"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/response"
+	v2 "github.com/MinaMamdouh2/URL-Shortener/business/web/v2"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/jwks"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/logger"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
 	"github.com/ardanlabs/conf/v3"
 	"go.uber.org/zap"
 )
@@ -71,11 +81,46 @@ func run(ctx context.Context, log *zap.SugaredLogger) error {
 			APIHost            string        `conf:"default:0.0.0.0:3000"`
 			DebugHost          string        `conf:"default:0.0.0.0:4000,mask"`
 			CORSAllowedOrigins []string      `conf:"default:*"`
+			// The route sets to mount, e.g. "v1;v2" to run both versions side by side during a migration. Every version
+			// serves its own readiness, liveness and docs routes. The JWKS and the token routes only exist in v1 so far,
+			// a binary serving only v2 can verify tokens but not issue them.
+			APIVersions []string `conf:"default:v1"`
 		}
 		Auth struct {
 			KeysFolder string `conf:"default:../../../zarf/keys/"`
 			ActiveKID  string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
 			Issuer     string `conf:"default:URL-Shortener"`
+			// How long the tokens issued by "/v1/users/token/:kid" are valid for, they are short lived since a client can
+			// get a new one with its refresh token.
+			TokenExpiry time.Duration `conf:"default:15m"`
+			// How long a refresh token can be exchanged for a new pair.
+			RefreshTokenExpiry time.Duration `conf:"default:720h"`
+			// How often the revoked tokens are loaded from the DB, a token revoked by another instance is accepted here
+			// for at most this long.
+			RevocationRefresh time.Duration `conf:"default:30s"`
+			// How long a user's enabled state is cached before it is checked against the DB again.
+			UserCacheTTL time.Duration `conf:"default:30s"`
+			// How long a public key is cached before it is asked for again.
+			KeyCacheTTL time.Duration `conf:"default:5m"`
+			// How often the keys folder is read again to pick up added and removed keys.
+			KeysPollInterval time.Duration `conf:"default:30s"`
+			// How long a removed key still verifies tokens, it should be longer than TokenExpiry.
+			KeysGracePeriod time.Duration `conf:"default:2h"`
+			// When set, tokens are verified against this JWKS instead of the keys folder, e.g. the
+			// "/.well-known/jwks.json" of the instance that issues them. The keys folder is still used for signing.
+			JWKSURL string
+			// How often the JWKS is fetched again.
+			JWKSRefresh time.Duration `conf:"default:5m"`
+		}
+		DB struct {
+			User         string `conf:"default:postgres"`
+			Password     string `conf:"default:admin,mask"`
+			Host         string `conf:"default:localhost"`
+			Port         int    `conf:"default:5432"`
+			Name         string `conf:"default:url-shortener"`
+			MaxIdleConns int    `conf:"default:2"`
+			MaxOpenConns int    `conf:"default:0"`
+			DisableTLS   bool   `conf:"default:true"`
 		}
 	}{
 		Version: conf.Version{
@@ -112,20 +157,79 @@ func run(ctx context.Context, log *zap.SugaredLogger) error {
 	// will get back from Kubernetes
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	// -------------------------------------------------------------------------
+	// Database Support
+
+	log.Infow("startup", "status", "initializing database support", "host", cfg.DB.Host)
+
+	db, err := sqldb.Open(sqldb.Config{
+		User:         cfg.DB.User,
+		Password:     cfg.DB.Password,
+		Host:         cfg.DB.Host,
+		Port:         cfg.DB.Port,
+		Name:         cfg.DB.Name,
+		MaxIdleConns: cfg.DB.MaxIdleConns,
+		MaxOpenConns: cfg.DB.MaxOpenConns,
+		DisableTLS:   cfg.DB.DisableTLS,
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+	defer func() {
+		log.Infow("shutdown", "status", "stopping database support", "host", cfg.DB.Host)
+		sqldb.Close(db)
+	}()
+
 	// -------------------------------------------------------------------------
 	// Initialize authentication support
 
 	log.Info("startup", "status", "initializing authentication support")
 
-	ks, err := keystore.NewFS(os.DirFS(cfg.Auth.KeysFolder))
+	keysFS := os.DirFS(cfg.Auth.KeysFolder)
+
+	ks, err := keystore.NewFS(keysFS)
 	if err != nil {
 		return fmt.Errorf("reading keys: %w", err)
 	}
 
+	// The active kid is only where we start, it can be switched at runtime once the next key is in the folder.
+	if err := ks.SetActiveKID(cfg.Auth.ActiveKID); err != nil {
+		return fmt.Errorf("setting active kid: %w", err)
+	}
+
+	var keyLookup auth.KeyLookup = ks
+	if cfg.Auth.JWKSURL != "" {
+		log.Infow("startup", "status", "verifying tokens with a remote jwks", "url", cfg.Auth.JWKSURL)
+
+		jwksLookup, err := jwks.NewLookup(ctx, jwks.LookupConfig{
+			URL:             cfg.Auth.JWKSURL,
+			RefreshInterval: cfg.Auth.JWKSRefresh,
+			OnError: func(err error) {
+				log.Errorw("jwks", "status", "refreshing jwks", "ERROR", err)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("constructing jwks lookup: %w", err)
+		}
+		defer func() {
+			log.Infow("shutdown", "status", "stopping jwks refresh")
+			jwksLookup.Close()
+		}()
+
+		keyLookup = auth.NewSplitKeyLookup(ks, jwksLookup)
+	}
+
+	// Auth checks on every request that the user in the token is still enabled, it does that through the user core.
+	// It also rejects the tokens that were revoked, those come from the session core.
 	authCfg := auth.Config{
-		Log:       log,
-		KeyLookup: ks,
-		Issuer:    cfg.Auth.Issuer,
+		Log:               log,
+		KeyLookup:         keyLookup,
+		Issuer:            cfg.Auth.Issuer,
+		UserLookup:        user.NewCore(log, userdb.NewStore(log, db)),
+		UserCacheTTL:      cfg.Auth.UserCacheTTL,
+		KeyCacheTTL:       cfg.Auth.KeyCacheTTL,
+		RevocationLookup:  session.NewCore(log, sessiondb.NewStore(log, db)),
+		RevocationRefresh: cfg.Auth.RevocationRefresh,
 	}
 
 	auth, err := auth.New(authCfg)
@@ -133,6 +237,25 @@ func run(ctx context.Context, log *zap.SugaredLogger) error {
 		return fmt.Errorf("constructing auth: %w", err)
 	}
 
+	// The keys folder is watched so keys can be rotated without a restart, auth has to forget the keys that
+	// changed or went away.
+	keysWatcher := ks.Watch(keystore.WatchConfig{
+		FS:       keysFS,
+		Interval: cfg.Auth.KeysPollInterval,
+		Grace:    cfg.Auth.KeysGracePeriod,
+		OnChange: func(kids []string) {
+			log.Infow("keys", "status", "keys changed", "kids", kids)
+			auth.DropKeys(kids...)
+		},
+		OnError: func(err error) {
+			log.Errorw("keys", "status", "reloading keys", "ERROR", err)
+		},
+	})
+	defer func() {
+		log.Infow("shutdown", "status", "stopping keys watcher")
+		keysWatcher.Close()
+	}()
+
 	// When we are logging the config, this line of code takes the build information we are logging and also puts it
 	// into the metrics.
 	// Also this will automatically execute the init function for the expvar which adds an endpoint
@@ -174,18 +297,56 @@ func run(ctx context.Context, log *zap.SugaredLogger) error {
 	// -------------------------------------------------------------------------
 	// Start API Service
 
-	log.Infow("startup", "status", "initializing V1 API support")
+	// Every version builds its own App and we mount each one under its own prefix, so the config decides which
+	// route sets this binary is serving.
+	apiMux := http.NewServeMux()
+
+	if err := checkAPIVersions(cfg.Web.APIVersions); err != nil {
+		return fmt.Errorf("api versions: %w", err)
+	}
+
+	for _, version := range cfg.Web.APIVersions {
+		log.Infow("startup", "status", "initializing API support", "version", version)
 
-	cfgMux := v1.APIMuxConfig{
-		Build:    build,
-		Shutdown: shutdown,
-		Log:      log,
-		Auth:     auth,
+		switch version {
+		case "v1":
+			cfgMux := v1.APIMuxConfig{
+				Build:              build,
+				Shutdown:           shutdown,
+				Log:                log,
+				Auth:               auth,
+				DB:                 db,
+				Keys:               ks,
+				TokenExpiry:        cfg.Auth.TokenExpiry,
+				RefreshTokenExpiry: cfg.Auth.RefreshTokenExpiry,
+				// The OpenAPI document is filled in by the groups as they bind their routes and served at
+				// "/v1/openapi.json".
+				Doc: openapi.New("URL-Shortener API", build, response.ErrorDocument{}),
+			}
+			// We call the v1.APIMux which needs "v1.APIMuxConfig" and a concrete value that implements "RouteAdder"
+			// "handlers.Routes{}" implements the Add function, it's Add function gets called in "v1.APIMux" in which
+			// it calls "hackgrp.Routes(router)" which registers the routes to the router
+			v1App := v1.APIMux(cfgMux, v1handlers.Routes{})
+			apiMux.Handle("/v1/", v1App)
+
+			// The JWKS lives at a well known location outside of the version, v1 is the one publishing it.
+			apiMux.Handle("/.well-known/", v1App)
+
+		case "v2":
+			cfgMux := v2.APIMuxConfig{
+				Build:    build,
+				Shutdown: shutdown,
+				Log:      log,
+				Auth:     auth,
+				DB:       db,
+				Doc:      openapi.New("URL-Shortener API", build, response.ErrorDocument{}),
+			}
+			apiMux.Handle("/v2/", v2.APIMux(cfgMux, v2handlers.Routes{}))
+
+		default:
+			return fmt.Errorf("unknown api version %q", version)
+		}
 	}
-	// We call the v1.APIMux which needs "v1.APIMuxConfig" and a concrete value that implements "RouteAdder"
-	// "handlers.Routes{}" implements the Add function, it's Add function gets called in "v1.APIMux" in which
-	// it calls "hackgrp.Routes(router)" which registers the routes to the router
-	apiMux := v1.APIMux(cfgMux, handlers.Routes{})
 
 	// Here we are not going to use the function ListenAndServe, we are gonna construct an HTTP server value which
 	// has the method ListenAndServe which has the facilities for a load shedded shutdown.
@@ -242,3 +403,21 @@ func run(ctx context.Context, log *zap.SugaredLogger) error {
 	}
 	return nil
 }
+
+// checkAPIVersions makes sure there is something to serve and every version is only mounted once, the mux panics on
+// a prefix that is registered twice.
+func checkAPIVersions(versions []string) error {
+	if len(versions) == 0 {
+		return errors.New("no version to mount")
+	}
+
+	seen := make(map[string]bool, len(versions))
+	for _, version := range versions {
+		if seen[version] {
+			return fmt.Errorf("version %q is listed more than once", version)
+		}
+		seen[version] = true
+	}
+
+	return nil
+}
@@ -7,9 +7,12 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Handlers manages the set of check endpoints.
@@ -21,21 +24,31 @@ import (
 type Handlers struct {
 	build string
 	log   *zap.SugaredLogger
+	db    *gorm.DB
 }
 
 // New constructs a Handlers api for the check group.
-func New(build string, log *zap.SugaredLogger) *Handlers {
+func New(build string, log *zap.SugaredLogger, db *gorm.DB) *Handlers {
 	return &Handlers{
 		build: build,
 		log:   log,
+		db:    db,
 	}
 }
 
 // Readiness checks if the database is ready and if not will return a 500 status.
 // Do not respond by just returning an error because further up in the call stack it will interpret that as a non-trusted error.
 func (h *Handlers) Readiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
 	status := "ok"
 	statusCode := http.StatusOK
+	if err := sqldb.StatusCheck(ctx, h.db); err != nil {
+		status = "db not ready"
+		statusCode = http.StatusInternalServerError
+		h.log.Infow("readiness failure", "status", status, "ERROR", err)
+	}
 
 	// Here the status is important
 	data := struct {
@@ -3,21 +3,27 @@ package checkgrp
 import (
 	"net/http"
 
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Config contains all the mandatory systems required by handlers.
+// The Version is the prefix the routes are bound under, every API version mounts this group for itself.
 type Config struct {
-	Build string
-	Log   *zap.SugaredLogger
+	Version string
+	Build   string
+	Log     *zap.SugaredLogger
+	DB      *gorm.DB
+	Doc     *openapi.Document
 }
 
 // Routes adds specific routes for this group.
 func Routes(app *web.App, cfg Config) {
-	const version = "v1"
+	version := cfg.Version
 
-	hdl := New(cfg.Build, cfg.Log)
+	hdl := New(cfg.Build, cfg.Log, cfg.DB)
 	// Bill didn't want to include middleware within the scope of these routes, we are binding them to port 3000
 	// It is important they are on port 3000 because our application traffic goes through that port.
 	// The problem that if I have the middleware getting involved with these 2 routes, we will get a lot of noise
@@ -26,4 +32,12 @@ func Routes(app *web.App, cfg Config) {
 	app.HandleNoMiddleware(http.MethodGet, version, "/readiness", hdl.Readiness)
 	app.HandleNoMiddleware(http.MethodGet, version, "/liveness", hdl.Liveness)
 
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/readiness", openapi.Operation{
+		Summary: "Reports if the service is ready to receive traffic",
+		Tag:     "checks",
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/liveness", openapi.Operation{
+		Summary: "Reports if the service is alive with build and host information",
+		Tag:     "checks",
+	})
 }
@@ -0,0 +1,43 @@
+// Package docgrp maintains the group of handlers for the API documentation.
+package docgrp
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+)
+
+// The viewer is a single self contained HTML file, no CDN scripts or fonts, so the documentation works on a machine
+// without network access. It is embedded so it ships inside the service binary.
+//
+//go:embed viewer.html
+var viewer []byte
+
+// Handlers manages the set of documentation endpoints.
+// We hold the App because the routes are read back from it on every call, by the time a request comes in every
+// group has been registered.
+type Handlers struct {
+	app *web.App
+	doc *openapi.Document
+}
+
+// New constructs a Handlers api for the doc group.
+func New(app *web.App, doc *openapi.Document) *Handlers {
+	return &Handlers{
+		app: app,
+		doc: doc,
+	}
+}
+
+// OpenAPI returns the OpenAPI document generated from the routes bound to the App.
+func (h *Handlers) OpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.doc.Spec(h.app.RegisteredRoutes()), http.StatusOK)
+}
+
+// Viewer returns the HTML page that renders the OpenAPI document.
+func (h *Handlers) Viewer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.RespondRaw(ctx, w, viewer, "text/html; charset=utf-8", http.StatusOK)
+}
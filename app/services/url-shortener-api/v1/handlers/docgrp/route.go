@@ -0,0 +1,33 @@
+package docgrp
+
+import (
+	"net/http"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+// The Version is the prefix the routes are bound under, every API version mounts this group for itself.
+type Config struct {
+	Version string
+	Doc     *openapi.Document
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	version := cfg.Version
+
+	hdl := New(app, cfg.Doc)
+	app.Handle(http.MethodGet, version, "/openapi.json", hdl.OpenAPI)
+	app.Handle(http.MethodGet, version, "/docs", hdl.Viewer)
+
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/openapi.json", openapi.Operation{
+		Summary: "OpenAPI document for this API",
+		Tag:     "docs",
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/docs", openapi.Operation{
+		Summary: "HTML viewer for the OpenAPI document",
+		Tag:     "docs",
+	})
+}
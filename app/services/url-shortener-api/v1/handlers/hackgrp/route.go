@@ -5,12 +5,14 @@ import (
 
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/mid"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
 )
 
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
 	Auth *auth.Auth
+	Doc  *openapi.Document
 }
 
 // Routes adds specific routes for this group using Gin.
@@ -22,4 +24,13 @@ func Routes(app *web.App, cfg Config) {
 	ruleAdmin := mid.Authorize(cfg.Auth, auth.RuleAdminOnly)
 	app.Handle(http.MethodGet, version, "/hack", Hack)
 	app.Handle(http.MethodGet, version, "/hackauth", Hack, authen, ruleAdmin)
+
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/hack", openapi.Operation{
+		Summary: "Randomly fails with a trusted error",
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/hackauth", openapi.Operation{
+		Summary: "Same as hack for admins only",
+		Tag:     "hack",
+		Auth:    true,
+	})
 }
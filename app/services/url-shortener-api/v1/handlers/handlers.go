@@ -2,21 +2,53 @@ package handlers
 
 import (
 	"github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers/checkgrp"
+	"github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers/docgrp"
 	"github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers/hackgrp"
+	"github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers/jwksgrp"
+	"github.com/MinaMamdouh2/URL-Shortener/app/services/url-shortener-api/v1/handlers/usergrp"
 	v1 "github.com/MinaMamdouh2/URL-Shortener/business/web/v1"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
 )
 
+// version is the prefix the v1 groups are bound under.
+const version = "v1"
+
+// Routes is the route set for the v1 API.
 type Routes struct{}
 
 // Add implements the RouterAdder interface
 func (Routes) Add(app *web.App, apiCfg v1.APIMuxConfig) {
 	hackgrp.Routes(app, hackgrp.Config{
 		Auth: apiCfg.Auth,
+		Doc:  apiCfg.Doc,
 	})
 
 	checkgrp.Routes(app, checkgrp.Config{
-		Build: apiCfg.Build,
-		Log:   apiCfg.Log,
+		Version: version,
+		Build:   apiCfg.Build,
+		Log:     apiCfg.Log,
+		DB:      apiCfg.DB,
+		Doc:     apiCfg.Doc,
+	})
+
+	usergrp.Routes(app, usergrp.Config{
+		Log:                apiCfg.Log,
+		Auth:               apiCfg.Auth,
+		DB:                 apiCfg.DB,
+		Doc:                apiCfg.Doc,
+		Keys:               apiCfg.Keys,
+		TokenExpiry:        apiCfg.TokenExpiry,
+		RefreshTokenExpiry: apiCfg.RefreshTokenExpiry,
+	})
+
+	jwksgrp.Routes(app, jwksgrp.Config{
+		Auth: apiCfg.Auth,
+		Keys: apiCfg.Keys,
+		Doc:  apiCfg.Doc,
+	})
+
+	docgrp.Routes(app, docgrp.Config{
+		Version: version,
+		Doc:     apiCfg.Doc,
 	})
 }
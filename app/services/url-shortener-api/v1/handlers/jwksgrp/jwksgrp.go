@@ -0,0 +1,65 @@
+// Package jwksgrp maintains the group of handlers for publishing and managing the keys we sign tokens with.
+package jwksgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/response"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+)
+
+// Handlers manages the set of jwks endpoints.
+type Handlers struct {
+	keys *keystore.KeyStore
+}
+
+// New constructs a Handlers api for the jwks group.
+func New(keys *keystore.KeyStore) *Handlers {
+	return &Handlers{
+		keys: keys,
+	}
+}
+
+// JWKS returns the public keys tokens are signed with, so other services can verify our tokens without having our
+// PEM files.
+// The set is built on every call, it is small, and it always reflects the keys the store has right now.
+func (h *Handlers) JWKS(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	set, err := h.keys.JWKS()
+	if err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+
+	// Verifiers are allowed to hold on to the set for a little while, they fetch again when they see a kid they
+	// don't know.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	return web.Respond(ctx, w, set, http.StatusOK)
+}
+
+// ActiveKey returns the kid new tokens are signed with.
+func (h *Handlers) ActiveKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, AppActiveKey{KID: h.keys.ActiveKID()}, http.StatusOK)
+}
+
+// SetActiveKey switches the kid new tokens are signed with. The key has to be in the store already, so rotating is
+// adding the new PEM file, waiting for it to be picked up and then switching to it.
+// The switch only applies to this instance, every instance of the service has to be told.
+func (h *Handlers) SetActiveKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppActiveKey
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := h.keys.SetActiveKID(app.KID); err != nil {
+		if errors.Is(err, keystore.ErrKIDNotFound) || errors.Is(err, keystore.ErrKIDRetired) {
+			return response.NewError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("setactivekid: %w", err)
+	}
+
+	return web.Respond(ctx, w, app, http.StatusOK)
+}
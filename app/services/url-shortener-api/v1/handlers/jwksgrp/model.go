@@ -0,0 +1,18 @@
+package jwksgrp
+
+import (
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+)
+
+// AppActiveKey represents the key new tokens are signed with.
+type AppActiveKey struct {
+	KID string `json:"kid" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppActiveKey) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
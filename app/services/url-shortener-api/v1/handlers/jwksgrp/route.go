@@ -0,0 +1,54 @@
+package jwksgrp
+
+import (
+	"net/http"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/mid"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/jwks"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Auth *auth.Auth
+	Keys *keystore.KeyStore
+	Doc  *openapi.Document
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	// The location of the set is a convention verifiers look for, so it is not under the version group.
+	const path = "/.well-known/jwks.json"
+
+	authen := mid.Authenticate(cfg.Auth)
+	ruleAdmin := mid.Authorize(cfg.Auth, auth.RuleAdminOnly)
+
+	hdl := New(cfg.Keys)
+	app.Handle(http.MethodGet, "", path, hdl.JWKS)
+	app.Handle(http.MethodGet, version, "/keys/active", hdl.ActiveKey, authen, ruleAdmin)
+	app.Handle(http.MethodPut, version, "/keys/active", hdl.SetActiveKey, authen, ruleAdmin)
+
+	cfg.Doc.Describe(http.MethodGet, path, openapi.Operation{
+		Summary:  "Public keys for verifying the tokens this service issues",
+		Tag:      "auth",
+		Response: jwks.Set{},
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/keys/active", openapi.Operation{
+		Summary:  "The kid new tokens are signed with, admin only",
+		Tag:      "auth",
+		Auth:     true,
+		Response: AppActiveKey{},
+	})
+	cfg.Doc.Describe(http.MethodPut, "/"+version+"/keys/active", openapi.Operation{
+		Summary:  "Switch the kid new tokens are signed with on this instance, admin only",
+		Tag:      "auth",
+		Auth:     true,
+		Request:  AppActiveKey{},
+		Response: AppActiveKey{},
+	})
+}
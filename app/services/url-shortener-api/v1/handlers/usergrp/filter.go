@@ -0,0 +1,62 @@
+package usergrp
+
+import (
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+	"github.com/google/uuid"
+)
+
+// parseFilter builds the QueryFilter out of the query string, every value is parsed into its business type first
+// and then set with the With API.
+// e.g. on query "name=Gopher&start_created_date=2019-03-24T00:00:00Z"
+func parseFilter(r *http.Request) (user.QueryFilter, error) {
+	values := r.URL.Query()
+
+	var filter user.QueryFilter
+
+	if userID := values.Get("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return user.QueryFilter{}, validate.NewFieldsError("user_id", err)
+		}
+		filter.WithUserID(id)
+	}
+
+	if name := values.Get("name"); name != "" {
+		filter.WithName(name)
+	}
+
+	if email := values.Get("email"); email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil {
+			return user.QueryFilter{}, validate.NewFieldsError("email", err)
+		}
+		filter.WithEmail(*addr)
+	}
+
+	if createdDate := values.Get("start_created_date"); createdDate != "" {
+		t, err := time.Parse(time.RFC3339, createdDate)
+		if err != nil {
+			return user.QueryFilter{}, validate.NewFieldsError("start_created_date", err)
+		}
+		filter.WithStartDateCreated(t)
+	}
+
+	if createdDate := values.Get("end_created_date"); createdDate != "" {
+		t, err := time.Parse(time.RFC3339, createdDate)
+		if err != nil {
+			return user.QueryFilter{}, validate.NewFieldsError("end_created_date", err)
+		}
+		filter.WithEndCreatedDate(t)
+	}
+
+	if err := filter.Validate(); err != nil {
+		return user.QueryFilter{}, err
+	}
+
+	return filter, nil
+}
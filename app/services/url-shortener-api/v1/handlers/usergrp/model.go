@@ -0,0 +1,189 @@
+package usergrp
+
+import (
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+)
+
+// These are the app layer models, they are what gets marshaled and unmarshaled, the business models never are.
+// Every field is a type JSON knows about, and the converters are where we parse those into the business types.
+
+// AppUser represents information about an individual user.
+type AppUser struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	Enabled     bool     `json:"enabled"`
+	DateCreated string   `json:"dateCreated"`
+	DateUpdated string   `json:"dateUpdated"`
+}
+
+func toAppUser(usr user.User) AppUser {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
+
+	return AppUser{
+		ID:          usr.ID.String(),
+		Name:        usr.Name,
+		Email:       usr.Email.Address,
+		Roles:       roles,
+		Enabled:     usr.Enabled,
+		DateCreated: usr.DateCreated.Format(time.RFC3339),
+		DateUpdated: usr.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppUsers(users []user.User) []AppUser {
+	items := make([]AppUser, len(users))
+	for i, usr := range users {
+		items[i] = toAppUser(usr)
+	}
+
+	return items
+}
+
+// =============================================================================
+
+// AppToken represents the tokens issued to a user. The access token is short lived, the refresh token is exchanged
+// for a new pair before it expires.
+type AppToken struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+// AppRefreshToken contains the refresh token to exchange for a new pair of tokens.
+type AppRefreshToken struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppRefreshToken) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// =============================================================================
+
+// AppNewUser contains information needed to create a new user.
+type AppNewUser struct {
+	Name            string   `json:"name" validate:"required,min=3"`
+	Email           string   `json:"email" validate:"required,email"`
+	Roles           []string `json:"roles" validate:"required,min=1,dive,oneof=ADMIN USER"`
+	Password        string   `json:"password" validate:"required,min=8"`
+	PasswordConfirm string   `json:"passwordConfirm" validate:"required,eqfield=Password"`
+}
+
+func toCoreNewUser(app AppNewUser) (user.NewUser, error) {
+	roles, err := parseRoles(app.Roles)
+	if err != nil {
+		return user.NewUser{}, err
+	}
+
+	addr, err := mail.ParseAddress(app.Email)
+	if err != nil {
+		return user.NewUser{}, validate.NewFieldsError("email", err)
+	}
+
+	usr := user.NewUser{
+		Name:            app.Name,
+		Email:           *addr,
+		Roles:           roles,
+		Password:        app.Password,
+		PasswordConfirm: app.PasswordConfirm,
+	}
+
+	return usr, nil
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewUser) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// =============================================================================
+
+// AppUpdateUser contains information needed to update a user.
+// Pointer semantics again, nil means leave the field as it is.
+// PasswordCurrent is only used to check a user changing their own password, it is never stored.
+type AppUpdateUser struct {
+	Name            *string  `json:"name" validate:"omitempty,min=3"`
+	Email           *string  `json:"email" validate:"omitempty,email"`
+	Roles           []string `json:"roles" validate:"omitempty,min=1,dive,oneof=ADMIN USER"`
+	Password        *string  `json:"password" validate:"omitempty,min=8"`
+	PasswordConfirm *string  `json:"passwordConfirm" validate:"omitempty,eqfield=Password"`
+	PasswordCurrent *string  `json:"passwordCurrent"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+func toCoreUpdateUser(app AppUpdateUser) (user.UpdateUser, error) {
+	var roles []user.Role
+	if app.Roles != nil {
+		var err error
+		roles, err = parseRoles(app.Roles)
+		if err != nil {
+			return user.UpdateUser{}, err
+		}
+	}
+
+	var addr *mail.Address
+	if app.Email != nil {
+		var err error
+		addr, err = mail.ParseAddress(*app.Email)
+		if err != nil {
+			return user.UpdateUser{}, validate.NewFieldsError("email", err)
+		}
+	}
+
+	nu := user.UpdateUser{
+		Name:            app.Name,
+		Email:           addr,
+		Roles:           roles,
+		Password:        app.Password,
+		PasswordConfirm: app.PasswordConfirm,
+		Enabled:         app.Enabled,
+	}
+
+	return nu, nil
+}
+
+// Validate checks the data in the model is considered clean.
+// A new password is only accepted when it comes with the confirmation.
+func (app AppUpdateUser) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+
+	if app.Password != nil && app.PasswordConfirm == nil {
+		return validate.NewFieldsError("passwordConfirm", fmt.Errorf("passwordConfirm is required when changing the password"))
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+func parseRoles(values []string) ([]user.Role, error) {
+	roles := make([]user.Role, len(values))
+	for i, value := range values {
+		role, err := user.ParseRole(value)
+		if err != nil {
+			return nil, validate.NewFieldsError("roles", err)
+		}
+		roles[i] = role
+	}
+
+	return roles, nil
+}
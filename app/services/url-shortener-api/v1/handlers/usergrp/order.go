@@ -0,0 +1,13 @@
+package usergrp
+
+import "github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+
+// orderByFields is the whitelist of fields the API can be ordered by, mapped from the API names to the business ones.
+// e.g. on query "orderBy=name,DESC"
+var orderByFields = map[string]string{
+	"user_id": user.OrderByID,
+	"name":    user.OrderByName,
+	"email":   user.OrderByEmail,
+	"roles":   user.OrderByRoles,
+	"enabled": user.OrderByEnabled,
+}
@@ -0,0 +1,121 @@
+package usergrp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session/stores/sessiondb"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user/stores/userdb"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/mid"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/response"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log  *zap.SugaredLogger
+	Auth *auth.Auth
+	DB   *gorm.DB
+	Doc  *openapi.Document
+	// The keys tokens are signed with, the token route signs with the active one unless it is asked for a kid.
+	Keys *keystore.KeyStore
+	// How long the tokens issued by the token route are valid for.
+	TokenExpiry time.Duration
+	// How long a refresh token can be exchanged for a new pair.
+	RefreshTokenExpiry time.Duration
+}
+
+// Routes adds specific routes for this group.
+// This is where the layers meet, the store is constructed with the DB, the core with the store and the handlers
+// with the core.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
+	sesCore := session.NewCore(cfg.Log, sessiondb.NewStore(cfg.Log, cfg.DB))
+
+	authen := mid.Authenticate(cfg.Auth)
+	ruleAdmin := mid.Authorize(cfg.Auth, auth.RuleAdminOnly)
+	ruleAdminOrSubject := mid.Authorize(cfg.Auth, auth.RuleAdminOrSubject)
+
+	hdl := New(usrCore, sesCore, cfg.Auth, cfg.Keys, cfg.TokenExpiry, cfg.RefreshTokenExpiry)
+	app.Handle(http.MethodGet, version, "/users/token", hdl.Token)
+	app.Handle(http.MethodGet, version, "/users/token/:kid", hdl.Token)
+	app.Handle(http.MethodPost, version, "/users/token/refresh", hdl.Refresh)
+	app.Handle(http.MethodPost, version, "/users/logout", hdl.Logout, authen)
+	app.Handle(http.MethodPost, version, "/users/:user_id/logout", hdl.LogoutAll, authen, ruleAdminOrSubject)
+	app.Handle(http.MethodPost, version, "/users", hdl.Create, authen, ruleAdmin)
+	app.Handle(http.MethodGet, version, "/users", hdl.Query, authen, ruleAdmin)
+	app.Handle(http.MethodGet, version, "/users/:user_id", hdl.QueryByID, authen, ruleAdminOrSubject)
+	app.Handle(http.MethodPut, version, "/users/:user_id", hdl.Update, authen, ruleAdminOrSubject)
+	app.Handle(http.MethodDelete, version, "/users/:user_id", hdl.Delete, authen, ruleAdminOrSubject)
+
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/users/token", openapi.Operation{
+		Summary:  "Issue a token signed with the active key, credentials in HTTP Basic auth",
+		Response: AppToken{},
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/users/token/:kid", openapi.Operation{
+		Summary:  "Issue a token signed with the key kid, credentials in HTTP Basic auth",
+		Response: AppToken{},
+	})
+	cfg.Doc.Describe(http.MethodPost, "/"+version+"/users/token/refresh", openapi.Operation{
+		Summary:  "Exchange a refresh token for a new pair of tokens, a refresh token can only be used once",
+		Request:  AppRefreshToken{},
+		Response: AppToken{},
+	})
+	cfg.Doc.Describe(http.MethodPost, "/"+version+"/users/logout", openapi.Operation{
+		Summary: "Revoke the token of the request and the session it came from",
+		Auth:    true,
+		Status:  http.StatusNoContent,
+	})
+	cfg.Doc.Describe(http.MethodPost, "/"+version+"/users/:user_id/logout", openapi.Operation{
+		Summary: "Revoke every session of a user, admin or the user itself",
+		Auth:    true,
+		Status:  http.StatusNoContent,
+	})
+	cfg.Doc.Describe(http.MethodPost, "/"+version+"/users", openapi.Operation{
+		Summary:  "Create a user, admin only",
+		Auth:     true,
+		Request:  AppNewUser{},
+		Response: AppUser{},
+		Status:   http.StatusCreated,
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/users", openapi.Operation{
+		Summary: "List users with filters, ordering and paging, admin only",
+		Auth:    true,
+		Query: []openapi.Param{
+			{Name: "page", Description: "page number, starts at 1"},
+			{Name: "rows", Description: "rows per page"},
+			{Name: "orderBy", Description: "field[,ASC|DESC] where field is one of user_id, name, email, roles, enabled"},
+			{Name: "user_id", Description: "filter by user id"},
+			{Name: "name", Description: "filter by a part of the name"},
+			{Name: "email", Description: "filter by email"},
+			{Name: "start_created_date", Description: "created on or after, RFC3339"},
+			{Name: "end_created_date", Description: "created on or before, RFC3339"},
+		},
+		Response: response.PageDocument[AppUser]{},
+	})
+	cfg.Doc.Describe(http.MethodGet, "/"+version+"/users/:user_id", openapi.Operation{
+		Summary:  "Get a user, admin or the user itself",
+		Auth:     true,
+		Response: AppUser{},
+	})
+	cfg.Doc.Describe(http.MethodPut, "/"+version+"/users/:user_id", openapi.Operation{
+		Summary:  "Update a user, admin or the user itself, only admins can change roles and enabled, users changing their own password send passwordCurrent",
+		Auth:     true,
+		Request:  AppUpdateUser{},
+		Response: AppUser{},
+	})
+	cfg.Doc.Describe(http.MethodDelete, "/"+version+"/users/:user_id", openapi.Operation{
+		Summary: "Delete a user, admin or the user itself",
+		Auth:    true,
+		Status:  http.StatusNoContent,
+	})
+}
@@ -0,0 +1,395 @@
+// Package usergrp maintains the group of handlers for user access.
+package usergrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"slices"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/paging"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/response"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// Handlers manages the set of user endpoints.
+type Handlers struct {
+	user          *user.Core
+	session       *session.Core
+	auth          *auth.Auth
+	keys          *keystore.KeyStore
+	tokenExpiry   time.Duration
+	refreshExpiry time.Duration
+}
+
+// New constructs a handlers for route access.
+func New(user *user.Core, session *session.Core, auth *auth.Auth, keys *keystore.KeyStore, tokenExpiry time.Duration, refreshExpiry time.Duration) *Handlers {
+	return &Handlers{
+		user:          user,
+		session:       session,
+		auth:          auth,
+		keys:          keys,
+		tokenExpiry:   tokenExpiry,
+		refreshExpiry: refreshExpiry,
+	}
+}
+
+// Create adds a new user to the system.
+func (h *Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppNewUser
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	nc, err := toCoreNewUser(app)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	usr, err := h.user.Create(ctx, nc)
+	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return response.NewError(user.ErrUniqueEmail, http.StatusConflict)
+		}
+		return fmt.Errorf("create: email[%s]: %w", nc.Email.Address, err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusCreated)
+}
+
+// Update updates a user in the system.
+// The subject is allowed to update their own user, but only an admin can change roles or enable/disable a user,
+// otherwise anybody could make themselves an admin.
+func (h *Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppUpdateUser
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	claims := auth.GetClaims(ctx)
+	isAdmin := slices.Contains(claims.Roles, user.RoleAdmin.Name())
+	if !isAdmin && (app.Roles != nil || app.Enabled != nil) {
+		return response.NewError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	userID := auth.GetUserID(ctx)
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return response.NewError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	// A stolen token shouldn't be enough to take over the account, a user changing their own password has to know
+	// the current one. Admins reset passwords without it.
+	if !isAdmin && app.Password != nil {
+		if app.PasswordCurrent == nil {
+			err := validate.NewFieldsError("passwordCurrent", errors.New("passwordCurrent is required when changing the password"))
+			return response.NewError(err, http.StatusBadRequest)
+		}
+		if err := h.user.CheckPassword(usr, *app.PasswordCurrent); err != nil {
+			err := validate.NewFieldsError("passwordCurrent", errors.New("passwordCurrent is not the current password"))
+			return response.NewError(err, http.StatusBadRequest)
+		}
+	}
+
+	uu, err := toCoreUpdateUser(app)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	usr, err = h.user.Update(ctx, usr, uu)
+	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return response.NewError(user.ErrUniqueEmail, http.StatusConflict)
+		}
+		return fmt.Errorf("update: userID[%s]: %w", userID, err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
+// Delete removes a user from the system.
+// Deleting a user that doesn't exist is not an error, the result is the same.
+func (h *Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userID := auth.GetUserID(ctx)
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	if err := h.user.Delete(ctx, usr); err != nil {
+		return fmt.Errorf("delete: userID[%s]: %w", userID, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns a list of users with paging.
+func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page, err := paging.ParseRequest(r)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	orderBy, err := order.Parse(r, orderByFields, user.DefaultOrderBy)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	users, err := h.user.Query(ctx, filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	total, err := h.user.Count(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+
+	return web.Respond(ctx, w, response.NewPageDocument(toAppUsers(users), total, page.Number, page.RowsPerPage), http.StatusOK)
+}
+
+// QueryByID returns a user by its ID.
+func (h *Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userID := auth.GetUserID(ctx)
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return response.NewError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
+// Token provides an API token and a refresh token for the authenticated user.
+// The credentials come in with HTTP Basic auth, the email as the user name. Anything wrong with the credentials is an
+// auth error, we never tell the caller which part was wrong.
+func (h *Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	// Without a kid in the path the token is signed with the active key, that is the one rotation moves forward.
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		kid = h.keys.ActiveKID()
+	}
+
+	email, pass, ok := r.BasicAuth()
+	if !ok {
+		return auth.NewAuthError("must provide email and password in Basic auth")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return auth.NewAuthError("invalid email format")
+	}
+
+	usr, err := h.user.Authenticate(ctx, *addr, pass)
+	if err != nil {
+		if errors.Is(err, user.ErrAuthenticationFailure) {
+			return auth.NewAuthError("%s", err)
+		}
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	access, err := h.signAccessToken(usr, kid)
+	if err != nil {
+		if errors.Is(err, keystore.ErrKIDNotFound) || errors.Is(err, keystore.ErrKIDRetired) {
+			return response.NewError(err, http.StatusBadRequest)
+		}
+		return err
+	}
+
+	refresh, _, err := h.session.Issue(ctx, access.newRefreshToken(usr.ID, h.refreshExpiry))
+	if err != nil {
+		return fmt.Errorf("issue: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, access.toAppToken(refresh), http.StatusOK)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens signed with the active key.
+// A refresh token can only be used once, using one again logs out every session that came from the same login.
+// The refresh token is only used up once the new access token is signed, so a failure before that doesn't cost the
+// client its login.
+func (h *Handlers) Refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppRefreshToken
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	rt, revs, err := h.session.Check(ctx, app.RefreshToken)
+	if err != nil {
+		return h.refreshError(err, revs)
+	}
+
+	// The user could have been disabled or deleted since the login, that ends the session.
+	usr, err := h.user.QueryByID(ctx, rt.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return auth.NewAuthError("%s", user.ErrAuthenticationFailure)
+		}
+		return fmt.Errorf("querybyid: userID[%s]: %w", rt.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return auth.NewAuthError("%s", user.ErrAuthenticationFailure)
+	}
+
+	access, err := h.signAccessToken(usr, h.keys.ActiveKID())
+	if err != nil {
+		return err
+	}
+
+	refresh, _, revs, err := h.session.Rotate(ctx, rt, access.newRefreshToken(usr.ID, h.refreshExpiry))
+	if err != nil {
+		return h.refreshError(err, revs)
+	}
+
+	return web.Respond(ctx, w, access.toAppToken(refresh), http.StatusOK)
+}
+
+// Logout revokes the access token of the request and the session it came from.
+func (h *Handlers) Logout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims := auth.GetClaims(ctx)
+
+	// Only the tokens we issue with an id can be revoked.
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return response.NewError(errors.New("token has no id, it can't be revoked"), http.StatusBadRequest)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return response.NewError(errors.New("token subject is not a user"), http.StatusBadRequest)
+	}
+
+	rev := session.Revocation{
+		JTI:         jti,
+		UserID:      userID,
+		DateExpires: claims.ExpiresAt.Time,
+		DateCreated: time.Now().UTC(),
+	}
+
+	revs, err := h.session.Revoke(ctx, rev)
+	if err != nil {
+		return fmt.Errorf("revoke: jti[%s]: %w", jti, err)
+	}
+	h.auth.Revoke(revs...)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// LogoutAll revokes every session of the user.
+func (h *Handlers) LogoutAll(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userID := auth.GetUserID(ctx)
+
+	revs, err := h.session.RevokeUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("revokeuser: userID[%s]: %w", userID, err)
+	}
+	h.auth.Revoke(revs...)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// =============================================================================
+
+// refreshError turns the errors of exchanging a refresh token into the response. A reused token revoked its family,
+// the access tokens of the family are rejected by this instance right away.
+func (h *Handlers) refreshError(err error, revs []session.Revocation) error {
+	switch {
+	case errors.Is(err, session.ErrTokenReused):
+		h.auth.Revoke(revs...)
+		return auth.NewAuthError("%s", err)
+	case errors.Is(err, session.ErrInvalidToken):
+		return auth.NewAuthError("%s", err)
+	}
+
+	return fmt.Errorf("refresh: %w", err)
+}
+
+// accessToken is a signed access token with what the refresh token issued with it has to know about it.
+type accessToken struct {
+	token   string
+	jti     uuid.UUID
+	expires time.Time
+}
+
+func (at accessToken) newRefreshToken(userID uuid.UUID, ttl time.Duration) session.NewRefreshToken {
+	return session.NewRefreshToken{
+		UserID:        userID,
+		AccessJTI:     at.jti,
+		AccessExpires: at.expires,
+		TTL:           ttl,
+	}
+}
+
+func (at accessToken) toAppToken(refresh string) AppToken {
+	return AppToken{
+		Token:        at.token,
+		RefreshToken: refresh,
+		ExpiresAt:    at.expires.Format(time.RFC3339),
+	}
+}
+
+// signAccessToken signs an access token for the user with the key of the kid.
+func (h *Handlers) signAccessToken(usr user.User, kid string) (accessToken, error) {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
+
+	now := time.Now().UTC()
+	expires := now.Add(h.tokenExpiry)
+
+	// Every access token gets an id, that is what it is revoked by.
+	jti := uuid.New()
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti.String(),
+			Subject:   usr.ID.String(),
+			Issuer:    h.auth.Issuer(),
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles: roles,
+	}
+
+	token, err := h.auth.GenerateToken(kid, claims)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("generatetoken: %w", err)
+	}
+
+	at := accessToken{
+		token:   token,
+		jti:     jti,
+		expires: expires,
+	}
+
+	return at, nil
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	_ "embed"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,7 +18,11 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/jwks"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/pemkey"
+	"github.com/ardanlabs/conf/v3"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 func main() {
@@ -23,7 +32,44 @@ func main() {
 }
 
 func genToken() error {
-	privateKey, err := getPrivateKey()
+	// The algorithm decides what kind of key gets generated when the key file doesn't exist yet, an existing key file
+	// has to be of the same kind.
+	// The service checks the subject is a user that exists and is enabled, so it has to be the id of a real user.
+	// Example: go run app/tooling/token/main.go --subject=5cf37266-3473-4006-984f-9325122678b7 --key-alg=ES256
+	var cfg struct {
+		Subject string `conf:"required,help:id of an enabled user the token is for"`
+		Key     struct {
+			Alg    string `conf:"default:RS256,help:one of RS256 ES256 ES384 EdDSA"`
+			KID    string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			Folder string `conf:"default:zarf/keys"`
+		}
+		// Tokens are short lived, the service hands out refresh tokens for anything longer.
+		Token struct {
+			Expiry time.Duration `conf:"default:1h"`
+		}
+	}
+
+	const prefix = "URL_SHORTENER"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	if _, err := uuid.Parse(cfg.Subject); err != nil {
+		return fmt.Errorf("subject has to be a user id: %w", err)
+	}
+
+	method := jwt.GetSigningMethod(cfg.Key.Alg)
+	if method == nil {
+		return fmt.Errorf("unknown algorithm %q", cfg.Key.Alg)
+	}
+
+	privateKey, err := getPrivateKey(cfg.Key.Folder, cfg.Key.KID, cfg.Key.Alg)
 	if err != nil {
 		return fmt.Errorf("failed to get Private Key %w", err)
 	}
@@ -32,15 +78,16 @@ func genToken() error {
 		Roles []string
 	}{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   "1234",
+			ID:        uuid.NewString(),
+			Subject:   cfg.Subject,
 			Issuer:    "URL-Shortener",
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(8760 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(cfg.Token.Expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		},
 		Roles: []string{"ADMIN"},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = cfg.Key.KID
 
 	str, err := token.SignedString(privateKey)
 	if err != nil {
@@ -51,8 +98,8 @@ func genToken() error {
 	fmt.Println("*******************")
 	// =========================================================================
 	// Validate token
-	// Only tokens signed with RS256 will be considered
-	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name}))
+	// Only tokens signed with the algorithm of the key will be considered
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{method.Alg()}))
 
 	var claims2 struct {
 		jwt.RegisteredClaims
@@ -63,7 +110,7 @@ func genToken() error {
 	// we should be using.
 	// In a more robust setup you'd inspect "t.Header["kid"]" to choose among multiple keys
 	keyFunc := func(t *jwt.Token) (interface{}, error) {
-		return &privateKey.PublicKey, nil
+		return privateKey.Public(), nil
 	}
 
 	tkn, err := parser.ParseWithClaims(str, &claims2, keyFunc)
@@ -83,31 +130,30 @@ func genToken() error {
 	return nil
 }
 
-func getPrivateKey() (*rsa.PrivateKey, error) {
-	keysDirPath := filepath.Join("zarf", "keys")
+func getPrivateKey(folder string, kid string, alg string) (crypto.Signer, error) {
 	// 1) Stat the directory
-	if _, err := os.Stat(keysDirPath); err != nil {
+	if _, err := os.Stat(folder); err != nil {
 		if os.IsNotExist(err) {
 			// 2a) It doesn't exist → create it (and any parent paths)
-			if mkErr := os.MkdirAll(keysDirPath, 0o755); mkErr != nil {
+			if mkErr := os.MkdirAll(folder, 0o755); mkErr != nil {
 				return nil, fmt.Errorf("failed to create keys directory %q: %w",
-					keysDirPath, mkErr)
+					folder, mkErr)
 			}
 		} else {
 			// 2b) Some other error (e.g. permissions) → bail out
 			return nil, fmt.Errorf("could not stat keys directory %q: %w",
-				keysDirPath, err)
+				folder, err)
 		}
 	}
 	// Path is relative to the project root, since I am running the main from the root folder
-	privatekeyFilePath := filepath.Join("zarf", "keys", "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem")
+	privatekeyFilePath := filepath.Join(folder, kid+".pem")
 
 	// Check if the file exists by opening it
 	privateKeyFile, err := os.Open(privatekeyFilePath)
 	if err != nil {
 		fmt.Println("Error trying to open private key file, err:", err)
-		fmt.Println("Generating private & public keys ...")
-		if err := genKeyFiles(); err != nil {
+		fmt.Println("Generating", alg, "private key ...")
+		if err := genKeyFile(privatekeyFilePath, alg); err != nil {
 			return nil, err
 		}
 		privateKeyFile, err = os.Open(privatekeyFilePath)
@@ -123,39 +169,83 @@ func getPrivateKey() (*rsa.PrivateKey, error) {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(privateKeyBytes)
-	if block == nil {
-		return nil, fmt.Errorf("no PEM block found in %q", privatekeyFilePath)
+	signer, err := pemkey.ParsePrivate(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %q: %w", privatekeyFilePath, err)
 	}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	// The file could have been there already, its kind of key has to match the algorithm asked for. Otherwise asking
+	// for ES256 with an RSA file would quietly sign RS256.
+	jwk, err := jwks.NewKey(kid, signer.Public())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key bytes %w", err)
+		return nil, fmt.Errorf("key in %q: %w", privatekeyFilePath, err)
 	}
-	return privateKey, nil
+	if jwk.Alg != alg {
+		return nil, fmt.Errorf("key in %q signs with %s, not %s", privatekeyFilePath, jwk.Alg, alg)
+	}
+
+	return signer, nil
 }
 
-func genKeyFiles() error {
-	// Generate a new private key.
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return fmt.Errorf("generating key: %w", err)
+func genKeyFile(privateKeyFilePath string, alg string) error {
+	// Generate a new private key of the kind the algorithm signs with, and encode it the way that kind of key is
+	// usually written so the PEM type tells what it is.
+	var privateBlock pem.Block
+	switch alg {
+	case jwt.SigningMethodRS256.Name:
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		privateBlock = pem.Block{
+			Type: "RSA PRIVATE KEY",
+			// We have to use this "x509.MarshalPKCS1PrivateKey" function to do this properly.
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}
+
+	case jwt.SigningMethodES256.Name, jwt.SigningMethodES384.Name:
+		curve := elliptic.P256()
+		if alg == jwt.SigningMethodES384.Name {
+			curve = elliptic.P384()
+		}
+		privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		der, err := x509.MarshalECPrivateKey(privateKey)
+		if err != nil {
+			return fmt.Errorf("marshaling key: %w", err)
+		}
+		privateBlock = pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}
+
+	case jwt.SigningMethodEdDSA.Alg():
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		// Ed25519 keys only have the PKCS8 form.
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return fmt.Errorf("marshaling key: %w", err)
+		}
+		privateBlock = pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: der,
+		}
+
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
 	}
 
 	// Create a file for the private key information in PEM form.
-	privateKeyFilePath := filepath.Join("zarf", "keys", "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem")
 	privateFile, err := os.Create(privateKeyFilePath)
 	if err != nil {
 		return fmt.Errorf("creating private file: %w", err)
 	}
 	defer privateFile.Close()
-	// Here we are encoding it into the pem formatting to write to disk
-	// Construct a PEM block for the private key.
-	privateBlock := pem.Block{
-		Type: "PRIVATE KEY",
-		// We have to use this "x509.MarshalPKCS1PrivateKey" function to do this properly.
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	}
 
 	// Write the private key to the private key file.
 	if err := pem.Encode(privateFile, &privateBlock); err != nil {
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken represents a refresh token issued to a user.
+// The token the client holds is never stored, only its hash, so a copy of the table can't be used to log in.
+// Every token that is rotated out of the same login shares the family, that is what gets revoked when a token is
+// used twice.
+// The access token issued with it is remembered, so revoking the refresh token can revoke that one too.
+type RefreshToken struct {
+	ID            uuid.UUID
+	FamilyID      uuid.UUID
+	UserID        uuid.UUID
+	TokenHash     string
+	AccessJTI     uuid.UUID
+	AccessExpires time.Time
+	DateCreated   time.Time
+	DateExpires   time.Time
+	// DateUsed is the zero value until the token is exchanged for a new one.
+	DateUsed time.Time
+	// DateRevoked is the zero value unless the token was revoked.
+	DateRevoked time.Time
+}
+
+// NewRefreshToken contains information needed to issue a refresh token.
+// On Rotate the user is the one of the rotated token, the UserID is ignored.
+type NewRefreshToken struct {
+	UserID        uuid.UUID
+	AccessJTI     uuid.UUID
+	AccessExpires time.Time
+	TTL           time.Duration
+}
+
+// Revocation represents an access token that was revoked before it expired. It only has to be remembered until then.
+type Revocation struct {
+	JTI         uuid.UUID
+	UserID      uuid.UUID
+	DateExpires time.Time
+	DateCreated time.Time
+}
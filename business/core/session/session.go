@@ -0,0 +1,244 @@
+// Package session provides the business access to refresh tokens and the revocation of access tokens.
+// Access tokens are short lived and can't be taken back once issued, a login is kept alive by exchanging a refresh
+// token for a new pair. Every refresh token can only be exchanged once, seeing one a second time means it was copied,
+// so the whole login is revoked.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Set of error variables for refresh tokens.
+var (
+	ErrInvalidToken = errors.New("refresh token is invalid or expired")
+	ErrTokenReused  = errors.New("refresh token was already used")
+)
+
+// Storer interface declares the behavior this package needs to persists and retrieve data.
+type Storer interface {
+	Create(ctx context.Context, rt RefreshToken) error
+	QueryByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	QueryByAccessJTI(ctx context.Context, jti uuid.UUID) (RefreshToken, error)
+	// Rotate sets the date the token was used and creates its successor in one transaction. It reports false, and
+	// creates nothing, when the token was already used or revoked. Marking it used has to be a single conditional
+	// update, two requests racing with the same token can't both win.
+	Rotate(ctx context.Context, usedID uuid.UUID, now time.Time, next RefreshToken) (bool, error)
+	// RevokeFamily and RevokeUser revoke the refresh tokens that aren't revoked yet and return them.
+	RevokeFamily(ctx context.Context, familyID uuid.UUID, now time.Time) ([]RefreshToken, error)
+	RevokeUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]RefreshToken, error)
+	// CreateRevocations ignores the jtis that are already revoked.
+	CreateRevocations(ctx context.Context, revs []Revocation) error
+	// QueryRevocations returns the revocations of the access tokens that haven't expired by now.
+	QueryRevocations(ctx context.Context, now time.Time) ([]Revocation, error)
+}
+
+// =============================================================================
+
+// Core manages the set of APIs for session access.
+type Core struct {
+	storer Storer
+	log    *zap.SugaredLogger
+}
+
+// NewCore constructs a core for session api access.
+func NewCore(log *zap.SugaredLogger, storer Storer) *Core {
+	return &Core{
+		storer: storer,
+		log:    log,
+	}
+}
+
+// Issue creates a refresh token for a new login and returns the token for the client with what was stored.
+func (c *Core) Issue(ctx context.Context, nrt NewRefreshToken) (string, RefreshToken, error) {
+	token, rt, err := newRefreshToken(uuid.New(), nrt)
+	if err != nil {
+		return "", RefreshToken{}, err
+	}
+
+	if err := c.storer.Create(ctx, rt); err != nil {
+		return "", RefreshToken{}, fmt.Errorf("create: %w", err)
+	}
+
+	return token, rt, nil
+}
+
+// Check finds the refresh token and makes sure it can still be exchanged, it doesn't use it up. That only happens in
+// Rotate, once the caller knows it can issue the new pair, so a failure in between doesn't cost the client its login.
+// A token that was already used is revoked together with its whole family, the refresh tokens and the access tokens
+// issued with them. The revocations are returned with ErrTokenReused so the caller can apply them right away.
+func (c *Core) Check(ctx context.Context, token string) (RefreshToken, []Revocation, error) {
+	rt, err := c.storer.QueryByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return RefreshToken{}, nil, ErrInvalidToken
+		}
+		return RefreshToken{}, nil, fmt.Errorf("querybyhash: %w", err)
+	}
+
+	switch {
+	case !rt.DateRevoked.IsZero(), time.Now().UTC().After(rt.DateExpires):
+		return RefreshToken{}, nil, ErrInvalidToken
+
+	case !rt.DateUsed.IsZero():
+		revs, err := c.reused(ctx, rt)
+		return RefreshToken{}, revs, err
+	}
+
+	return rt, nil, nil
+}
+
+// Rotate exchanges a refresh token returned by Check for its successor in the same family, the token can't be used
+// again after this. The successor is only stored when the exchange happens, a failure leaves the token as it was.
+// Losing the exchange to another request with the same token is a reuse and is handled the same way as in Check.
+func (c *Core) Rotate(ctx context.Context, rt RefreshToken, nrt NewRefreshToken) (string, RefreshToken, []Revocation, error) {
+	nrt.UserID = rt.UserID
+
+	token, next, err := newRefreshToken(rt.FamilyID, nrt)
+	if err != nil {
+		return "", RefreshToken{}, nil, err
+	}
+
+	rotated, err := c.storer.Rotate(ctx, rt.ID, next.DateCreated, next)
+	if err != nil {
+		return "", RefreshToken{}, nil, fmt.Errorf("rotate: %w", err)
+	}
+
+	// Somebody else used it between Check and now.
+	if !rotated {
+		revs, err := c.reused(ctx, rt)
+		return "", RefreshToken{}, revs, err
+	}
+
+	return token, next, nil, nil
+}
+
+// Revoke revokes the login the access token belongs to, the refresh tokens of its family and the access tokens issued
+// with them, and the access token itself. It is what logging out does.
+func (c *Core) Revoke(ctx context.Context, rev Revocation) ([]Revocation, error) {
+	revs := []Revocation{rev}
+
+	rt, err := c.storer.QueryByAccessJTI(ctx, rev.JTI)
+	switch {
+	case errors.Is(err, sqldb.ErrDBNotFound):
+		// The access token wasn't issued with a refresh token, there is no family to revoke.
+	case err != nil:
+		return nil, fmt.Errorf("querybyaccessjti: %w", err)
+	default:
+		rts, err := c.storer.RevokeFamily(ctx, rt.FamilyID, time.Now().UTC())
+		if err != nil {
+			return nil, fmt.Errorf("revokefamily: %w", err)
+		}
+		revs = append(revs, c.accessRevocations(rts)...)
+	}
+
+	if err := c.storer.CreateRevocations(ctx, revs); err != nil {
+		return nil, fmt.Errorf("createrevocations: %w", err)
+	}
+
+	return revs, nil
+}
+
+// RevokeUser revokes every login of the user, it is what logging out everywhere does.
+func (c *Core) RevokeUser(ctx context.Context, userID uuid.UUID) ([]Revocation, error) {
+	rts, err := c.storer.RevokeUser(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("revokeuser: userID[%s]: %w", userID, err)
+	}
+
+	revs := c.accessRevocations(rts)
+	if err := c.storer.CreateRevocations(ctx, revs); err != nil {
+		return nil, fmt.Errorf("createrevocations: userID[%s]: %w", userID, err)
+	}
+
+	return revs, nil
+}
+
+// QueryRevocations returns the access tokens that are revoked and not expired yet.
+func (c *Core) QueryRevocations(ctx context.Context) ([]Revocation, error) {
+	revs, err := c.storer.QueryRevocations(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return revs, nil
+}
+
+// =============================================================================
+
+func (c *Core) reused(ctx context.Context, rt RefreshToken) ([]Revocation, error) {
+	c.log.Warnw("refresh token reused", "userID", rt.UserID, "familyID", rt.FamilyID)
+
+	rts, err := c.storer.RevokeFamily(ctx, rt.FamilyID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("revokefamily: %w", err)
+	}
+
+	revs := c.accessRevocations(rts)
+	if err := c.storer.CreateRevocations(ctx, revs); err != nil {
+		return nil, fmt.Errorf("createrevocations: %w", err)
+	}
+
+	return revs, ErrTokenReused
+}
+
+// accessRevocations builds the revocations for the access tokens issued with the refresh tokens, the ones that
+// already expired don't need one.
+func (c *Core) accessRevocations(rts []RefreshToken) []Revocation {
+	now := time.Now().UTC()
+
+	var revs []Revocation
+	for _, rt := range rts {
+		if now.After(rt.AccessExpires) {
+			continue
+		}
+		revs = append(revs, Revocation{
+			JTI:         rt.AccessJTI,
+			UserID:      rt.UserID,
+			DateExpires: rt.AccessExpires,
+			DateCreated: now,
+		})
+	}
+
+	return revs
+}
+
+// newRefreshToken generates the token for the client and the refresh token to store for it in the family.
+func newRefreshToken(familyID uuid.UUID, nrt NewRefreshToken) (string, RefreshToken, error) {
+	// 32 random bytes can't be guessed, so a fast hash is enough to store it, unlike a password.
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", RefreshToken{}, fmt.Errorf("generating token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now().UTC()
+
+	rt := RefreshToken{
+		ID:            uuid.New(),
+		FamilyID:      familyID,
+		UserID:        nrt.UserID,
+		TokenHash:     hashToken(token),
+		AccessJTI:     nrt.AccessJTI,
+		AccessExpires: nrt.AccessExpires.UTC(),
+		DateCreated:   now,
+		DateExpires:   now.Add(nrt.TTL),
+	}
+
+	return token, rt, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,284 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// memStore is an in memory Storer with the same conditional updates as the database store.
+type memStore struct {
+	mu   sync.Mutex
+	rts  map[uuid.UUID]session.RefreshToken
+	revs map[uuid.UUID]session.Revocation
+	// failCreate makes the next Rotate fail after the token was marked used, the transaction has to undo that.
+	failCreate bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		rts:  make(map[uuid.UUID]session.RefreshToken),
+		revs: make(map[uuid.UUID]session.Revocation),
+	}
+}
+
+func (s *memStore) Create(ctx context.Context, rt session.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rts[rt.ID] = rt
+	return nil
+}
+
+func (s *memStore) QueryByHash(ctx context.Context, tokenHash string) (session.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rt := range s.rts {
+		if rt.TokenHash == tokenHash {
+			return rt, nil
+		}
+	}
+	return session.RefreshToken{}, sqldb.ErrDBNotFound
+}
+
+func (s *memStore) QueryByAccessJTI(ctx context.Context, jti uuid.UUID) (session.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rt := range s.rts {
+		if rt.AccessJTI == jti {
+			return rt, nil
+		}
+	}
+	return session.RefreshToken{}, sqldb.ErrDBNotFound
+}
+
+func (s *memStore) Rotate(ctx context.Context, usedID uuid.UUID, now time.Time, next session.RefreshToken) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, exists := s.rts[usedID]
+	if !exists || !rt.DateUsed.IsZero() || !rt.DateRevoked.IsZero() {
+		return false, nil
+	}
+
+	if s.failCreate {
+		s.failCreate = false
+		return false, errors.New("insert failed")
+	}
+
+	rt.DateUsed = now
+	s.rts[usedID] = rt
+	s.rts[next.ID] = next
+
+	return true, nil
+}
+
+func (s *memStore) revoke(match func(session.RefreshToken) bool, now time.Time) []session.RefreshToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked []session.RefreshToken
+	for id, rt := range s.rts {
+		if match(rt) && rt.DateRevoked.IsZero() {
+			rt.DateRevoked = now
+			s.rts[id] = rt
+			revoked = append(revoked, rt)
+		}
+	}
+	return revoked
+}
+
+func (s *memStore) RevokeFamily(ctx context.Context, familyID uuid.UUID, now time.Time) ([]session.RefreshToken, error) {
+	return s.revoke(func(rt session.RefreshToken) bool { return rt.FamilyID == familyID }, now), nil
+}
+
+func (s *memStore) RevokeUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]session.RefreshToken, error) {
+	return s.revoke(func(rt session.RefreshToken) bool { return rt.UserID == userID }, now), nil
+}
+
+func (s *memStore) CreateRevocations(ctx context.Context, revs []session.Revocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rev := range revs {
+		if _, exists := s.revs[rev.JTI]; !exists {
+			s.revs[rev.JTI] = rev
+		}
+	}
+	return nil
+}
+
+func (s *memStore) QueryRevocations(ctx context.Context, now time.Time) ([]session.Revocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revs []session.Revocation
+	for _, rev := range s.revs {
+		if rev.DateExpires.After(now) {
+			revs = append(revs, rev)
+		}
+	}
+	return revs, nil
+}
+
+// =============================================================================
+
+func newAccess(userID uuid.UUID, ttl time.Duration) session.NewRefreshToken {
+	return session.NewRefreshToken{
+		UserID:        userID,
+		AccessJTI:     uuid.New(),
+		AccessExpires: time.Now().Add(15 * time.Minute),
+		TTL:           ttl,
+	}
+}
+
+func jtis(revs []session.Revocation) []uuid.UUID {
+	ids := make([]uuid.UUID, len(revs))
+	for i, rev := range revs {
+		ids[i] = rev.JTI
+	}
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	return ids
+}
+
+func Test_Rotation(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	core := session.NewCore(zap.NewNop().Sugar(), store)
+
+	userID := uuid.New()
+
+	first := newAccess(userID, time.Hour)
+	token1, rt1, err := core.Issue(ctx, first)
+	if err != nil {
+		t.Fatalf("Should be able to issue: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// First use, the token is exchanged for its successor in the same family.
+
+	checked, _, err := core.Check(ctx, token1)
+	if err != nil {
+		t.Fatalf("Should accept a fresh token: %s", err)
+	}
+	if checked.ID != rt1.ID {
+		t.Fatalf("Should find the issued token: got %s, want %s", checked.ID, rt1.ID)
+	}
+
+	second := newAccess(uuid.New(), time.Hour)
+	token2, rt2, _, err := core.Rotate(ctx, checked, second)
+	if err != nil {
+		t.Fatalf("Should be able to rotate: %s", err)
+	}
+	if rt2.FamilyID != rt1.FamilyID || rt2.UserID != userID {
+		t.Fatalf("Should stay in the family and with the user: got %+v", rt2)
+	}
+
+	if _, _, err := core.Check(ctx, token2); err != nil {
+		t.Fatalf("Should accept the successor: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Reuse, the used token shows up again. The whole family is revoked, with the access tokens issued with it.
+
+	_, revs, err := core.Check(ctx, token1)
+	if !errors.Is(err, session.ErrTokenReused) {
+		t.Fatalf("Should detect the reuse: got %v", err)
+	}
+
+	want := jtis([]session.Revocation{{JTI: first.AccessJTI}, {JTI: second.AccessJTI}})
+	if got := jtis(revs); !slices.Equal(got, want) {
+		t.Fatalf("Should revoke the access tokens of the family: got %v, want %v", got, want)
+	}
+
+	stored, _ := core.QueryRevocations(ctx)
+	if got := jtis(stored); !slices.Equal(got, want) {
+		t.Fatalf("Should store the revocations for the other instances: got %v, want %v", got, want)
+	}
+
+	if _, _, err := core.Check(ctx, token2); !errors.Is(err, session.ErrInvalidToken) {
+		t.Fatalf("Should not accept the successor of a reused token: got %v", err)
+	}
+}
+
+func Test_RotationRace(t *testing.T) {
+	ctx := context.Background()
+	core := session.NewCore(zap.NewNop().Sugar(), newMemStore())
+
+	token, _, err := core.Issue(ctx, newAccess(uuid.New(), time.Hour))
+	if err != nil {
+		t.Fatalf("Should be able to issue: %s", err)
+	}
+
+	// Two requests with the same token both pass the check, only one can win the exchange.
+	rtA, _, errA := core.Check(ctx, token)
+	rtB, _, errB := core.Check(ctx, token)
+	if errA != nil || errB != nil {
+		t.Fatalf("Should accept the token before it is used: %v, %v", errA, errB)
+	}
+
+	if _, _, _, err := core.Rotate(ctx, rtA, newAccess(uuid.New(), time.Hour)); err != nil {
+		t.Fatalf("Should let the first one rotate: %s", err)
+	}
+	if _, _, _, err := core.Rotate(ctx, rtB, newAccess(uuid.New(), time.Hour)); !errors.Is(err, session.ErrTokenReused) {
+		t.Fatalf("Should treat the second one as a reuse: got %v", err)
+	}
+}
+
+func Test_RotationFailureKeepsToken(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	core := session.NewCore(zap.NewNop().Sugar(), store)
+
+	token, _, err := core.Issue(ctx, newAccess(uuid.New(), time.Hour))
+	if err != nil {
+		t.Fatalf("Should be able to issue: %s", err)
+	}
+
+	rt, _, err := core.Check(ctx, token)
+	if err != nil {
+		t.Fatalf("Should accept the token: %s", err)
+	}
+
+	store.failCreate = true
+	if _, _, _, err := core.Rotate(ctx, rt, newAccess(uuid.New(), time.Hour)); err == nil || errors.Is(err, session.ErrTokenReused) {
+		t.Fatalf("Should fail the rotation without calling it a reuse: got %v", err)
+	}
+
+	// The client retries with the same token, it wasn't used up.
+	rt, _, err = core.Check(ctx, token)
+	if err != nil {
+		t.Fatalf("Should still accept the token after a failed rotation: %s", err)
+	}
+	if _, _, _, err := core.Rotate(ctx, rt, newAccess(uuid.New(), time.Hour)); err != nil {
+		t.Fatalf("Should be able to rotate on the retry: %s", err)
+	}
+}
+
+func Test_ExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	core := session.NewCore(zap.NewNop().Sugar(), newMemStore())
+
+	token, _, err := core.Issue(ctx, newAccess(uuid.New(), -time.Minute))
+	if err != nil {
+		t.Fatalf("Should be able to issue: %s", err)
+	}
+
+	if _, _, err := core.Check(ctx, token); !errors.Is(err, session.ErrInvalidToken) {
+		t.Fatalf("Should not accept an expired token: got %v", err)
+	}
+
+	if _, _, err := core.Check(ctx, "not-a-token"); !errors.Is(err, session.ErrInvalidToken) {
+		t.Fatalf("Should not accept an unknown token: got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package sessiondb
+
+import (
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/google/uuid"
+)
+
+// dbRefreshToken represent the structure we need for moving data between the app and the database.
+// The dates that aren't set yet are NULL in the DB and the zero value in the business model.
+type dbRefreshToken struct {
+	ID            uuid.UUID  `gorm:"column:id;type:uuid;primaryKey"`
+	FamilyID      uuid.UUID  `gorm:"column:family_id;type:uuid"`
+	UserID        uuid.UUID  `gorm:"column:user_id;type:uuid"`
+	TokenHash     string     `gorm:"column:token_hash"`
+	AccessJTI     uuid.UUID  `gorm:"column:access_jti;type:uuid"`
+	AccessExpires time.Time  `gorm:"column:access_expires"`
+	DateCreated   time.Time  `gorm:"column:date_created"`
+	DateExpires   time.Time  `gorm:"column:date_expires"`
+	DateUsed      *time.Time `gorm:"column:date_used"`
+	DateRevoked   *time.Time `gorm:"column:date_revoked"`
+}
+
+// TableName tells GORM which table this model maps to.
+func (dbRefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+func toDBRefreshToken(rt session.RefreshToken) dbRefreshToken {
+	return dbRefreshToken{
+		ID:            rt.ID,
+		FamilyID:      rt.FamilyID,
+		UserID:        rt.UserID,
+		TokenHash:     rt.TokenHash,
+		AccessJTI:     rt.AccessJTI,
+		AccessExpires: rt.AccessExpires,
+		DateCreated:   rt.DateCreated,
+		DateExpires:   rt.DateExpires,
+		DateUsed:      toDBTime(rt.DateUsed),
+		DateRevoked:   toDBTime(rt.DateRevoked),
+	}
+}
+
+func toCoreRefreshToken(dbRT dbRefreshToken) session.RefreshToken {
+	return session.RefreshToken{
+		ID:            dbRT.ID,
+		FamilyID:      dbRT.FamilyID,
+		UserID:        dbRT.UserID,
+		TokenHash:     dbRT.TokenHash,
+		AccessJTI:     dbRT.AccessJTI,
+		AccessExpires: dbRT.AccessExpires.In(time.UTC),
+		DateCreated:   dbRT.DateCreated.In(time.UTC),
+		DateExpires:   dbRT.DateExpires.In(time.UTC),
+		DateUsed:      toCoreTime(dbRT.DateUsed),
+		DateRevoked:   toCoreTime(dbRT.DateRevoked),
+	}
+}
+
+func toCoreRefreshTokenSlice(dbRTs []dbRefreshToken) []session.RefreshToken {
+	rts := make([]session.RefreshToken, len(dbRTs))
+	for i, dbRT := range dbRTs {
+		rts[i] = toCoreRefreshToken(dbRT)
+	}
+	return rts
+}
+
+// =============================================================================
+
+// dbRevocation represent the structure we need for moving data between the app and the database.
+type dbRevocation struct {
+	JTI         uuid.UUID `gorm:"column:jti;type:uuid;primaryKey"`
+	UserID      uuid.UUID `gorm:"column:user_id;type:uuid"`
+	DateExpires time.Time `gorm:"column:date_expires"`
+	DateCreated time.Time `gorm:"column:date_created"`
+}
+
+// TableName tells GORM which table this model maps to.
+func (dbRevocation) TableName() string {
+	return "revoked_tokens"
+}
+
+func toDBRevocation(rev session.Revocation) dbRevocation {
+	return dbRevocation{
+		JTI:         rev.JTI,
+		UserID:      rev.UserID,
+		DateExpires: rev.DateExpires,
+		DateCreated: rev.DateCreated,
+	}
+}
+
+func toCoreRevocation(dbRev dbRevocation) session.Revocation {
+	return session.Revocation{
+		JTI:         dbRev.JTI,
+		UserID:      dbRev.UserID,
+		DateExpires: dbRev.DateExpires.In(time.UTC),
+		DateCreated: dbRev.DateCreated.In(time.UTC),
+	}
+}
+
+// =============================================================================
+
+func toDBTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func toCoreTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.In(time.UTC)
+}
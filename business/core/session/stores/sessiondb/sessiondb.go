@@ -0,0 +1,156 @@
+// Package sessiondb contains refresh token and revocation related CRUD functionality.
+package sessiondb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Store manages the set of APIs for session database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *gorm.DB
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zap.SugaredLogger, db *gorm.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new refresh token into the database.
+func (s *Store) Create(ctx context.Context, rt session.RefreshToken) error {
+	dbRT := toDBRefreshToken(rt)
+
+	if err := s.db.WithContext(ctx).Create(&dbRT).Error; err != nil {
+		return fmt.Errorf("create: %w", sqldb.TranslateError(err))
+	}
+
+	return nil
+}
+
+// QueryByHash gets the refresh token with the hash from the database.
+func (s *Store) QueryByHash(ctx context.Context, tokenHash string) (session.RefreshToken, error) {
+	var dbRT dbRefreshToken
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(&dbRT).Error; err != nil {
+		return session.RefreshToken{}, fmt.Errorf("querybyhash: %w", sqldb.TranslateError(err))
+	}
+
+	return toCoreRefreshToken(dbRT), nil
+}
+
+// QueryByAccessJTI gets the refresh token issued with the access token from the database.
+func (s *Store) QueryByAccessJTI(ctx context.Context, jti uuid.UUID) (session.RefreshToken, error) {
+	var dbRT dbRefreshToken
+	if err := s.db.WithContext(ctx).Where("access_jti = ?", jti).Take(&dbRT).Error; err != nil {
+		return session.RefreshToken{}, fmt.Errorf("querybyaccessjti: %w", sqldb.TranslateError(err))
+	}
+
+	return toCoreRefreshToken(dbRT), nil
+}
+
+// Rotate sets the date the token was used, only when it isn't used or revoked yet, and inserts its successor. Both
+// happen in one transaction, the token is never used up without its successor being stored.
+func (s *Store) Rotate(ctx context.Context, usedID uuid.UUID, now time.Time, next session.RefreshToken) (bool, error) {
+	dbNext := toDBRefreshToken(next)
+
+	var rotated bool
+
+	f := func(tx *gorm.DB) error {
+		res := tx.Model(&dbRefreshToken{}).
+			Where("id = ? AND date_used IS NULL AND date_revoked IS NULL", usedID).
+			Update("date_used", now)
+		if res.Error != nil {
+			return fmt.Errorf("markused: %w", sqldb.TranslateError(res.Error))
+		}
+
+		// Somebody else got there first, there is nothing to roll back.
+		if res.RowsAffected != 1 {
+			return nil
+		}
+
+		if err := tx.Create(&dbNext).Error; err != nil {
+			return fmt.Errorf("create: %w", sqldb.TranslateError(err))
+		}
+
+		rotated = true
+		return nil
+	}
+
+	if err := s.db.WithContext(ctx).Transaction(f); err != nil {
+		return false, fmt.Errorf("rotate: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// RevokeFamily revokes the refresh tokens of the family that aren't revoked yet.
+func (s *Store) RevokeFamily(ctx context.Context, familyID uuid.UUID, now time.Time) ([]session.RefreshToken, error) {
+	return s.revoke(ctx, "family_id = ? AND date_revoked IS NULL", familyID, now)
+}
+
+// RevokeUser revokes the refresh tokens of the user that aren't revoked yet.
+func (s *Store) RevokeUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]session.RefreshToken, error) {
+	return s.revoke(ctx, "user_id = ? AND date_revoked IS NULL", userID, now)
+}
+
+// CreateRevocations inserts the revocations into the database, a jti that is already revoked is left as it is.
+func (s *Store) CreateRevocations(ctx context.Context, revs []session.Revocation) error {
+	if len(revs) == 0 {
+		return nil
+	}
+
+	dbRevs := make([]dbRevocation, len(revs))
+	for i, rev := range revs {
+		dbRevs[i] = toDBRevocation(rev)
+	}
+
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&dbRevs).Error; err != nil {
+		return fmt.Errorf("createrevocations: %w", sqldb.TranslateError(err))
+	}
+
+	return nil
+}
+
+// QueryRevocations retrieves the revocations of the access tokens that haven't expired yet.
+func (s *Store) QueryRevocations(ctx context.Context, now time.Time) ([]session.Revocation, error) {
+	var dbRevs []dbRevocation
+	if err := s.db.WithContext(ctx).Where("date_expires > ?", now).Find(&dbRevs).Error; err != nil {
+		return nil, fmt.Errorf("queryrevocations: %w", sqldb.TranslateError(err))
+	}
+
+	revs := make([]session.Revocation, len(dbRevs))
+	for i, dbRev := range dbRevs {
+		revs[i] = toCoreRevocation(dbRev)
+	}
+
+	return revs, nil
+}
+
+// =============================================================================
+
+// revoke sets the revoked date on the matching refresh tokens and returns them. Postgres gives us the rows that were
+// updated back in the same statement, so a token revoked at the same time by somebody else isn't returned twice.
+func (s *Store) revoke(ctx context.Context, where string, id uuid.UUID, now time.Time) ([]session.RefreshToken, error) {
+	var dbRTs []dbRefreshToken
+	err := s.db.WithContext(ctx).
+		Model(&dbRTs).
+		Clauses(clause.Returning{}).
+		Where(where, id).
+		Update("date_revoked", now).Error
+	if err != nil {
+		return nil, fmt.Errorf("revoke: %w", sqldb.TranslateError(err))
+	}
+
+	return toCoreRefreshTokenSlice(dbRTs), nil
+}
@@ -24,17 +24,16 @@ import (
 // If we made it a string, we are not forcing the app layer nor we are giving it any tooling to make sure that they give
 // me a role that is going to be well defined in the package, we are going to define a type.
 // Also if any business model had a set of tags for marshaling that is a smell, that enforces us to know that these
-// models are not what get marshaled and unmarshaled.
-
+// models are not what get marshaled and unmarshaled, the same goes for the DB, the store has it's own model.
 type User struct {
-	ID           uuid.UUID    `gorm:"column:id;type:uuid;primaryKey"`
-	Name         string       `gorm:"column:name"`
-	Email        mail.Address `gorm:"column:email"`
-	Roles        []Role       `gorm:"column:roles;type:text[]"` // TODO: Look into pq.StringArray to replace []Role
-	PasswordHash []byte       `gorm:"column:password_has"`
-	Enabled      bool         `gorm:"column:enabled"`
-	DateCreated  time.Time    `gorm:"column:date_created"`
-	DateUpdated  time.Time    `gorm:"column:date_updated"`
+	ID           uuid.UUID
+	Name         string
+	Email        mail.Address
+	Roles        []Role
+	PasswordHash []byte
+	Enabled      bool
+	DateCreated  time.Time
+	DateUpdated  time.Time
 }
 
 // NewUser contains information needed to create a new user.
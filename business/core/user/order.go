@@ -0,0 +1,17 @@
+package user
+
+import "github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByID, order.ASC)
+
+// Set of fields that the results can be ordered by. These are the names of the fields in the business layer, the app
+// layer maps it's API names into these and the store maps these into its columns, so nobody outside of the store
+// knows what the columns are called.
+const (
+	OrderByID      = "user_id"
+	OrderByName    = "name"
+	OrderByEmail   = "email"
+	OrderByRoles   = "roles"
+	OrderByEnabled = "enabled"
+)
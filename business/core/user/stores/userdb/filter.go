@@ -0,0 +1,44 @@
+package userdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"gorm.io/gorm"
+)
+
+// applyFilter adds a WHERE clause to the query for every field of the filter that is set.
+// The values are always passed as arguments, never concatenated into the query.
+func applyFilter(tx *gorm.DB, filter user.QueryFilter) *gorm.DB {
+	if filter.ID != nil {
+		tx = tx.Where("id = ?", *filter.ID)
+	}
+
+	if filter.Name != nil {
+		tx = tx.Where(`name LIKE ? ESCAPE '\'`, fmt.Sprintf("%%%s%%", escapeLike(*filter.Name)))
+	}
+
+	if filter.Email != nil {
+		tx = tx.Where("email = ?", filter.Email.Address)
+	}
+
+	if filter.StartCreatedDate != nil {
+		tx = tx.Where("date_created >= ?", filter.StartCreatedDate.UTC())
+	}
+
+	if filter.EndCreatedDate != nil {
+		tx = tx.Where("date_created <= ?", filter.EndCreatedDate.UTC())
+	}
+
+	return tx
+}
+
+// likeEscaper escapes the characters that mean something to LIKE, so a name filter of "50%" looks for "50%" and not
+// for every name starting with "50". The escape character is escaped as well.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes the value match itself literally in a LIKE pattern that uses ESCAPE '\'.
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
@@ -0,0 +1,22 @@
+package userdb
+
+import "testing"
+
+func Test_EscapeLike(t *testing.T) {
+	tt := []struct {
+		value string
+		want  string
+	}{
+		{value: "mina", want: "mina"},
+		{value: "50%", want: `50\%`},
+		{value: "a_b", want: `a\_b`},
+		{value: `a\b`, want: `a\\b`},
+		{value: `%_\`, want: `\%\_\\`},
+	}
+
+	for _, tst := range tt {
+		if got := escapeLike(tst.value); got != tst.want {
+			t.Errorf("Should escape %q: got %q, want %q", tst.value, got, tst.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package userdb
+
+import (
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// dbUser represent the structure we need for moving data between the app and the database.
+// The business model has no idea how it is stored, types like mail.Address and []Role have no meaning for the DB,
+// so the store has it's own model and converts between the two.
+type dbUser struct {
+	ID           uuid.UUID      `gorm:"column:id;type:uuid;primaryKey"`
+	Name         string         `gorm:"column:name"`
+	Email        string         `gorm:"column:email"`
+	Roles        pq.StringArray `gorm:"column:roles;type:text[]"`
+	PasswordHash string         `gorm:"column:password_hash"`
+	Enabled      bool           `gorm:"column:enabled"`
+	DateCreated  time.Time      `gorm:"column:date_created"`
+	DateUpdated  time.Time      `gorm:"column:date_updated"`
+}
+
+// TableName tells GORM which table this model maps to.
+func (dbUser) TableName() string {
+	return "users"
+}
+
+func toDBUser(usr user.User) dbUser {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
+
+	return dbUser{
+		ID:           usr.ID,
+		Name:         usr.Name,
+		Email:        usr.Email.Address,
+		Roles:        roles,
+		PasswordHash: string(usr.PasswordHash),
+		Enabled:      usr.Enabled,
+		DateCreated:  usr.DateCreated.UTC(),
+		DateUpdated:  usr.DateUpdated.UTC(),
+	}
+}
+
+// toCoreUser parses the roles coming back from the DB, a role we don't know about means the data can't be trusted.
+func toCoreUser(dbUsr dbUser) (user.User, error) {
+	roles := make([]user.Role, len(dbUsr.Roles))
+	for i, value := range dbUsr.Roles {
+		role, err := user.ParseRole(value)
+		if err != nil {
+			return user.User{}, fmt.Errorf("parse role: %w", err)
+		}
+		roles[i] = role
+	}
+
+	usr := user.User{
+		ID:           dbUsr.ID,
+		Name:         dbUsr.Name,
+		Email:        mail.Address{Address: dbUsr.Email},
+		Roles:        roles,
+		PasswordHash: []byte(dbUsr.PasswordHash),
+		Enabled:      dbUsr.Enabled,
+		DateCreated:  dbUsr.DateCreated.In(time.Local),
+		DateUpdated:  dbUsr.DateUpdated.In(time.Local),
+	}
+
+	return usr, nil
+}
+
+func toCoreUserSlice(dbUsers []dbUser) ([]user.User, error) {
+	usrs := make([]user.User, len(dbUsers))
+	for i, dbUsr := range dbUsers {
+		usr, err := toCoreUser(dbUsr)
+		if err != nil {
+			return nil, err
+		}
+		usrs[i] = usr
+	}
+
+	return usrs, nil
+}
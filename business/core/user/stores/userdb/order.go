@@ -0,0 +1,31 @@
+package userdb
+
+import (
+	"fmt"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+)
+
+// orderByFields maps the business order fields into the columns of the users table.
+var orderByFields = map[string]string{
+	user.OrderByID:      "id",
+	user.OrderByName:    "name",
+	user.OrderByEmail:   "email",
+	user.OrderByRoles:   "roles",
+	user.OrderByEnabled: "enabled",
+}
+
+// orderByClause builds the ORDER BY clause, only fields in the map can end up in the query.
+func orderByClause(orderBy order.By) (string, error) {
+	by, exists := orderByFields[orderBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	if orderBy.Direction != order.ASC && orderBy.Direction != order.DESC {
+		return "", fmt.Errorf("direction %q does not exist", orderBy.Direction)
+	}
+
+	return by + " " + orderBy.Direction, nil
+}
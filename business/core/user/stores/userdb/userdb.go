@@ -6,6 +6,14 @@
 package userdb
 
 import (
+	"context"
+	"fmt"
+	"net/mail"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -23,3 +31,105 @@ func NewStore(log *zap.SugaredLogger, db *gorm.DB) *Store {
 		db:  db,
 	}
 }
+
+// Create inserts a new user into the database.
+func (s *Store) Create(ctx context.Context, usr user.User) error {
+	dbUsr := toDBUser(usr)
+
+	if err := s.db.WithContext(ctx).Create(&dbUsr).Error; err != nil {
+		return fmt.Errorf("create: %w", sqldb.TranslateError(err))
+	}
+
+	return nil
+}
+
+// Update replaces a user document in the database.
+// We name the columns on purpose, GORM skips zero values when updating from a struct and a disabled user is a zero
+// value for Enabled.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
+	dbUsr := toDBUser(usr)
+
+	err := s.db.WithContext(ctx).
+		Model(&dbUsr).
+		Select("name", "email", "roles", "password_hash", "enabled", "date_updated").
+		Updates(&dbUsr).Error
+	if err != nil {
+		return fmt.Errorf("update: %w", sqldb.TranslateError(err))
+	}
+
+	return nil
+}
+
+// Delete removes a user from the database.
+func (s *Store) Delete(ctx context.Context, usr user.User) error {
+	if err := s.db.WithContext(ctx).Where("id = ?", usr.ID).Delete(&dbUser{}).Error; err != nil {
+		return fmt.Errorf("delete: %w", sqldb.TranslateError(err))
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing users from the database.
+func (s *Store) Query(ctx context.Context, filter user.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbUsrs []dbUser
+	err = applyFilter(s.db.WithContext(ctx), filter).
+		Order(orderByClause).
+		Offset((pageNumber - 1) * rowsPerPage).
+		Limit(rowsPerPage).
+		Find(&dbUsrs).Error
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", sqldb.TranslateError(err))
+	}
+
+	usrs, err := toCoreUserSlice(dbUsrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return usrs, nil
+}
+
+// Count returns the total number of users in the DB.
+func (s *Store) Count(ctx context.Context, filter user.QueryFilter) (int, error) {
+	var count int64
+	if err := applyFilter(s.db.WithContext(ctx).Model(&dbUser{}), filter).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count: %w", sqldb.TranslateError(err))
+	}
+
+	return int(count), nil
+}
+
+// QueryByID gets the specified user from the database.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	var dbUsr dbUser
+	if err := s.db.WithContext(ctx).Where("id = ?", userID).Take(&dbUsr).Error; err != nil {
+		return user.User{}, fmt.Errorf("querybyid: %w", sqldb.TranslateError(err))
+	}
+
+	return toCoreUser(dbUsr)
+}
+
+// QueryByIDs gets the specified users from the database.
+func (s *Store) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]user.User, error) {
+	var dbUsrs []dbUser
+	if err := s.db.WithContext(ctx).Where("id IN ?", userIDs).Find(&dbUsrs).Error; err != nil {
+		return nil, fmt.Errorf("querybyids: %w", sqldb.TranslateError(err))
+	}
+
+	return toCoreUserSlice(dbUsrs)
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (user.User, error) {
+	var dbUsr dbUser
+	if err := s.db.WithContext(ctx).Where("email = ?", email.Address).Take(&dbUsr).Error; err != nil {
+		return user.User{}, fmt.Errorf("querybyemail: %w", sqldb.TranslateError(err))
+	}
+
+	return toCoreUser(dbUsr)
+}
@@ -3,16 +3,31 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/mail"
 	"time"
 
 	"github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Set of error variables for CRUD operations.
+// These are the business level errors, the storer errors never leave this package, so the app layer only ever has to
+// know about these to decide what to respond with.
+var (
+	ErrNotFound              = errors.New("user not found")
+	ErrUniqueEmail           = errors.New("email is not unique")
+	ErrAuthenticationFailure = errors.New("authentication failed")
+)
+
+// dummyHash is compared against when there is no password to check, an unknown email or a disabled user, so those
+// take as long as a wrong password. It has the same cost as the hashes we store.
+var dummyHash = []byte("$2a$10$19VkdT3ZBXMinsYZFWzxZuXKLttqLjXsc1Qjm7NQiQTU.Tp5LM2T.")
+
 // Storer interface declares the behavior this package needs to persists and retrieve data.
 // Bill always focused on this idea of small interface that provide the sort of this generic functionality,
 // your reader, your writer, he is always focused on the idea of precision.
@@ -87,8 +102,153 @@ func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
 	}
 
 	if err := c.storer.Create(ctx, usr); err != nil {
-		return User{}, fmt.Errorf("create user: %w", err)
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return User{}, fmt.Errorf("create: %w", ErrUniqueEmail)
+		}
+		return User{}, fmt.Errorf("create: %w", err)
+	}
+
+	return usr, nil
+}
+
+// Update modifies information about a user.
+// Only the fields that are set in the UpdateUser get applied to the user, and a new password gets hashed again.
+func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error) {
+	if uu.Name != nil {
+		usr.Name = *uu.Name
+	}
+
+	if uu.Email != nil {
+		usr.Email = *uu.Email
+	}
+
+	if uu.Roles != nil {
+		usr.Roles = uu.Roles
+	}
+
+	if uu.Password != nil {
+		pw, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		}
+		usr.PasswordHash = pw
+	}
+
+	if uu.Enabled != nil {
+		usr.Enabled = *uu.Enabled
+	}
+
+	usr.DateUpdated = time.Now()
+
+	if err := c.storer.Update(ctx, usr); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return User{}, fmt.Errorf("update: %w", ErrUniqueEmail)
+		}
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	return usr, nil
+}
+
+// Delete removes the specified user.
+func (c *Core) Delete(ctx context.Context, usr User) error {
+	if err := c.storer.Delete(ctx, usr); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing users.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error) {
+	users, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return users, nil
+}
+
+// Count returns the total number of users.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	count, err := c.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByID finds the user by the specified ID.
+func (c *Core) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	user, err := c.storer.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return User{}, fmt.Errorf("query: userID[%s]: %w", userID, ErrNotFound)
+		}
+		return User{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return user, nil
+}
+
+// QueryByIDs finds the users by the specified IDs.
+func (c *Core) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]User, error) {
+	users, err := c.storer.QueryByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query: userIDs[%s]: %w", userIDs, err)
+	}
+
+	return users, nil
+}
+
+// QueryByEmail finds the user by a specified user email.
+func (c *Core) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
+	user, err := c.storer.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return User{}, fmt.Errorf("query: email[%s]: %w", email.Address, ErrNotFound)
+		}
+		return User{}, fmt.Errorf("query: email[%s]: %w", email.Address, err)
+	}
+
+	return user, nil
+}
+
+// =============================================================================
+
+// Authenticate finds a user by their email and verifies their password. On success it returns a User representing
+// this user. The claims can then be used to generate a token for future authentication.
+// An unknown email, a disabled user and a wrong password all fail the same way and take the same time, we don't want
+// to tell the caller which emails exist in the system.
+func (c *Core) Authenticate(ctx context.Context, email mail.Address, password string) (User, error) {
+	usr, err := c.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return User{}, fmt.Errorf("authenticate: email[%s]: %w", email.Address, ErrAuthenticationFailure)
+		}
+		return User{}, fmt.Errorf("authenticate: %w", err)
+	}
+
+	if !usr.Enabled {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return User{}, fmt.Errorf("authenticate: email[%s] disabled: %w", email.Address, ErrAuthenticationFailure)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+		return User{}, fmt.Errorf("authenticate: email[%s]: %w", email.Address, ErrAuthenticationFailure)
+	}
+
+	return usr, nil
+}
+
+// CheckPassword verifies the password is the current password of the user, it is asked for before a user changes
+// their own password.
+func (c *Core) CheckPassword(usr User, password string) error {
+	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+		return fmt.Errorf("checkpassword: userID[%s]: %w", usr.ID, ErrAuthenticationFailure)
 	}
 
-	return User{}, nil
+	return nil
 }
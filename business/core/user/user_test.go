@@ -0,0 +1,359 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/sqldb"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memStore is an in memory Storer that fails the same way the database store does.
+type memStore struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]user.User
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		users: make(map[uuid.UUID]user.User),
+	}
+}
+
+func (s *memStore) Create(ctx context.Context, usr user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(usr) {
+		return sqldb.ErrDBDuplicatedEntry
+	}
+	s.users[usr.ID] = usr
+	return nil
+}
+
+func (s *memStore) Update(ctx context.Context, usr user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[usr.ID]; !exists {
+		return sqldb.ErrDBNotFound
+	}
+	if s.emailTaken(usr) {
+		return sqldb.ErrDBDuplicatedEntry
+	}
+	s.users[usr.ID] = usr
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, usr user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, usr.ID)
+	return nil
+}
+
+func (s *memStore) Query(ctx context.Context, filter user.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]user.User, 0, len(s.users))
+	for _, usr := range s.users {
+		users = append(users, usr)
+	}
+	return users, nil
+}
+
+func (s *memStore) Count(ctx context.Context, filter user.QueryFilter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.users), nil
+}
+
+func (s *memStore) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usr, exists := s.users[userID]
+	if !exists {
+		return user.User{}, sqldb.ErrDBNotFound
+	}
+	return usr, nil
+}
+
+func (s *memStore) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []user.User
+	for _, id := range userIDs {
+		if usr, exists := s.users[id]; exists {
+			users = append(users, usr)
+		}
+	}
+	return users, nil
+}
+
+func (s *memStore) QueryByEmail(ctx context.Context, email mail.Address) (user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, usr := range s.users {
+		if usr.Email.Address == email.Address {
+			return usr, nil
+		}
+	}
+	return user.User{}, sqldb.ErrDBNotFound
+}
+
+// emailTaken reports if another user already has the email of the user, the caller holds the lock.
+func (s *memStore) emailTaken(usr user.User) bool {
+	for _, other := range s.users {
+		if other.ID != usr.ID && other.Email.Address == usr.Email.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+
+func newUser(t *testing.T, core *user.Core, email string, password string) user.User {
+	t.Helper()
+
+	usr, err := core.Create(context.Background(), user.NewUser{
+		Name:            "Mina",
+		Email:           mail.Address{Address: email},
+		Roles:           []user.Role{user.RoleUser},
+		Password:        password,
+		PasswordConfirm: password,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create the user: %s", err)
+	}
+
+	return usr
+}
+
+func Test_Create(t *testing.T) {
+	core := user.NewCore(zap.NewNop().Sugar(), newMemStore())
+
+	usr := newUser(t, core, "mina@example.com", "gophers")
+
+	if !usr.Enabled {
+		t.Error("Should create the user enabled")
+	}
+	if string(usr.PasswordHash) == "gophers" {
+		t.Error("Should never store the password itself")
+	}
+	if err := core.CheckPassword(usr, "gophers"); err != nil {
+		t.Errorf("Should hash the password so it can be checked: %s", err)
+	}
+
+	_, err := core.Create(context.Background(), user.NewUser{
+		Name:     "Other",
+		Email:    mail.Address{Address: "mina@example.com"},
+		Password: "gophers",
+	})
+	if !errors.Is(err, user.ErrUniqueEmail) {
+		t.Errorf("Should reject a second user with the same email, got %v", err)
+	}
+}
+
+func Test_Update(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	disabled := false
+	email := mail.Address{Address: "new@example.com"}
+
+	tt := []struct {
+		name  string
+		uu    user.UpdateUser
+		check func(t *testing.T, before user.User, after user.User)
+	}{
+		{
+			name: "nothing",
+			uu:   user.UpdateUser{},
+			check: func(t *testing.T, before user.User, after user.User) {
+				if after.Name != before.Name || after.Email != before.Email || !slices.Equal(after.Roles, before.Roles) ||
+					string(after.PasswordHash) != string(before.PasswordHash) || after.Enabled != before.Enabled {
+					t.Errorf("Should leave every field as it was, got %+v", after)
+				}
+			},
+		},
+		{
+			name: "name",
+			uu:   user.UpdateUser{Name: ptr("Bill")},
+			check: func(t *testing.T, before user.User, after user.User) {
+				if after.Name != "Bill" {
+					t.Errorf("Should change the name, got %q", after.Name)
+				}
+				if after.Email != before.Email || string(after.PasswordHash) != string(before.PasswordHash) {
+					t.Error("Should leave the other fields as they were")
+				}
+			},
+		},
+		{
+			name: "email and roles",
+			uu:   user.UpdateUser{Email: &email, Roles: []user.Role{user.RoleAdmin}},
+			check: func(t *testing.T, before user.User, after user.User) {
+				if after.Email != email || !slices.Equal(after.Roles, []user.Role{user.RoleAdmin}) {
+					t.Errorf("Should change the email and roles, got %s %v", after.Email.Address, after.Roles)
+				}
+				if after.Name != before.Name {
+					t.Error("Should leave the name as it was")
+				}
+			},
+		},
+		{
+			name: "password",
+			uu:   user.UpdateUser{Password: ptr("new-gophers")},
+			check: func(t *testing.T, before user.User, after user.User) {
+				if bcrypt.CompareHashAndPassword(after.PasswordHash, []byte("new-gophers")) != nil {
+					t.Error("Should hash the new password")
+				}
+				if after.Name != before.Name {
+					t.Error("Should leave the name as it was")
+				}
+			},
+		},
+		{
+			name: "disable",
+			uu:   user.UpdateUser{Enabled: &disabled},
+			check: func(t *testing.T, before user.User, after user.User) {
+				if after.Enabled {
+					t.Error("Should disable the user")
+				}
+			},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			store := newMemStore()
+			core := user.NewCore(zap.NewNop().Sugar(), store)
+
+			before := newUser(t, core, "mina@example.com", "gophers")
+
+			after, err := core.Update(context.Background(), before, tst.uu)
+			if err != nil {
+				t.Fatalf("Should be able to update: %s", err)
+			}
+
+			if !after.DateUpdated.After(before.DateUpdated) {
+				t.Error("Should move the update date")
+			}
+			if after.ID != before.ID || !after.DateCreated.Equal(before.DateCreated) {
+				t.Error("Should never change the id or the creation date")
+			}
+
+			stored, err := store.QueryByID(context.Background(), before.ID)
+			if err != nil {
+				t.Fatalf("Should find the stored user: %s", err)
+			}
+			tst.check(t, before, stored)
+		})
+	}
+}
+
+func Test_UpdateUniqueEmail(t *testing.T) {
+	core := user.NewCore(zap.NewNop().Sugar(), newMemStore())
+
+	newUser(t, core, "taken@example.com", "gophers")
+	usr := newUser(t, core, "mina@example.com", "gophers")
+
+	taken := mail.Address{Address: "taken@example.com"}
+	if _, err := core.Update(context.Background(), usr, user.UpdateUser{Email: &taken}); !errors.Is(err, user.ErrUniqueEmail) {
+		t.Errorf("Should reject an email another user has, got %v", err)
+	}
+}
+
+func Test_QueryByIDNotFound(t *testing.T) {
+	core := user.NewCore(zap.NewNop().Sugar(), newMemStore())
+
+	if _, err := core.QueryByID(context.Background(), uuid.New()); !errors.Is(err, user.ErrNotFound) {
+		t.Errorf("Should translate a missing row into ErrNotFound, got %v", err)
+	}
+}
+
+func Test_Authenticate(t *testing.T) {
+	store := newMemStore()
+	core := user.NewCore(zap.NewNop().Sugar(), store)
+
+	usr := newUser(t, core, "mina@example.com", "gophers")
+
+	disabled := newUser(t, core, "disabled@example.com", "gophers")
+	enabled := false
+	if _, err := core.Update(context.Background(), disabled, user.UpdateUser{Enabled: &enabled}); err != nil {
+		t.Fatalf("Should be able to disable the user: %s", err)
+	}
+
+	tt := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  bool
+	}{
+		{name: "right password", email: "mina@example.com", password: "gophers"},
+		{name: "wrong password", email: "mina@example.com", password: "rust", wantErr: true},
+		{name: "unknown email", email: "nobody@example.com", password: "gophers", wantErr: true},
+		{name: "disabled user, right password", email: "disabled@example.com", password: "gophers", wantErr: true},
+	}
+
+	durations := make(map[string]time.Duration)
+
+	for _, tst := range tt {
+		start := time.Now()
+		got, err := core.Authenticate(context.Background(), mail.Address{Address: tst.email}, tst.password)
+		durations[tst.name] = time.Since(start)
+
+		if !tst.wantErr {
+			if err != nil {
+				t.Errorf("%s: Should authenticate: %s", tst.name, err)
+			}
+			if got.ID != usr.ID {
+				t.Errorf("%s: Should return the user, got %s", tst.name, got.ID)
+			}
+			continue
+		}
+
+		// All the failures look the same to the caller, so they can't be used to find out which emails exist.
+		if !errors.Is(err, user.ErrAuthenticationFailure) {
+			t.Errorf("%s: Should fail with ErrAuthenticationFailure, got %v", tst.name, err)
+		}
+		if errors.Is(err, user.ErrNotFound) {
+			t.Errorf("%s: Should not say the user wasn't found: %s", tst.name, err)
+		}
+	}
+
+	// An unknown or disabled user still pays for a bcrypt compare. Without the dummy hash they return in microseconds
+	// while a wrong password takes tens of milliseconds, the margin is wide so a busy machine doesn't fail the test.
+	wrong := durations["wrong password"]
+	for _, name := range []string{"unknown email", "disabled user, right password"} {
+		if durations[name] < wrong/4 {
+			t.Errorf("%s: Should take about as long as a wrong password, took %s against %s", name, durations[name], wrong)
+		}
+	}
+}
+
+func Test_CheckPassword(t *testing.T) {
+	core := user.NewCore(zap.NewNop().Sugar(), newMemStore())
+
+	usr := newUser(t, core, "mina@example.com", "gophers")
+
+	if err := core.CheckPassword(usr, "gophers"); err != nil {
+		t.Errorf("Should accept the current password: %s", err)
+	}
+	if err := core.CheckPassword(usr, "rust"); !errors.Is(err, user.ErrAuthenticationFailure) {
+		t.Errorf("Should reject another password with ErrAuthenticationFailure, got %v", err)
+	}
+}
@@ -11,4 +11,37 @@ CREATE TABLE users (
 	date_updated  TIMESTAMP,
 
 	PRIMARY KEY (id)
-);
\ No newline at end of file
+);
+
+-- Version: 1.2
+-- Description: Add enabled to users
+ALTER TABLE users ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Version: 1.3
+-- Description: Create tables for refresh tokens and revoked access tokens
+CREATE TABLE refresh_tokens (
+	id             UUID,
+	family_id      UUID NOT NULL,
+	user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash     TEXT NOT NULL UNIQUE,
+	access_jti     UUID NOT NULL,
+	access_expires TIMESTAMP NOT NULL,
+	date_created   TIMESTAMP NOT NULL,
+	date_expires   TIMESTAMP NOT NULL,
+	date_used      TIMESTAMP,
+	date_revoked   TIMESTAMP,
+
+	PRIMARY KEY (id)
+);
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+CREATE INDEX refresh_tokens_access_jti_idx ON refresh_tokens (access_jti);
+
+CREATE TABLE revoked_tokens (
+	jti          UUID,
+	user_id      UUID NOT NULL,
+	date_expires TIMESTAMP NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (jti)
+);
@@ -46,7 +46,10 @@ func NewBy(field string, direction string) By {
 // that's what our application layer going to be, but at the business layer we can cheat a bit because we are only using
 // this function in the app layer against a request looking for the query string.
 // e.g. on query "orderBy=name,desc"
-func Parse(r *http.Request, defaultOrder By) (By, error) {
+// The fieldMappings is the whitelist of fields the caller is allowed to order by, keyed by the name the API exposes
+// and mapped to the name the store understands. This way a caller can never inject an arbitrary ORDER BY column,
+// anything that is not in the map is rejected as a field error.
+func Parse(r *http.Request, fieldMappings map[string]string, defaultOrder By) (By, error) {
 	//TODO: Test the case for "orderBy=name,asc&orderBy=createdAt,desc"
 	v := r.URL.Query().Get("orderBy")
 
@@ -69,10 +72,17 @@ func Parse(r *http.Request, defaultOrder By) (By, error) {
 	default:
 		return By{}, validate.NewFieldsError(v, errors.New("unknown order field"))
 	}
-	// Checks wether the parsed direction is in the directions map
+	// Checks whether the parsed direction is in the directions map
 	if _, exists := directions[by.Direction]; !exists {
 		return By{}, validate.NewFieldsError(v, fmt.Errorf("unknown direction: %s", by.Direction))
 	}
 
+	// Checks whether the parsed field is in the whitelist and swap the API name for the mapped one.
+	field, exists := fieldMappings[by.Field]
+	if !exists {
+		return By{}, validate.NewFieldsError(v, fmt.Errorf("unknown field: %s", by.Field))
+	}
+	by.Field = field
+
 	return by, nil
 }
@@ -0,0 +1,54 @@
+package order_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/data/order"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+)
+
+func Test_Parse(t *testing.T) {
+	fieldMappings := map[string]string{
+		"name":    "name",
+		"user_id": "id",
+	}
+	defaultOrder := order.NewBy("id", order.ASC)
+
+	tt := []struct {
+		name    string
+		query   string
+		want    order.By
+		wantErr bool
+	}{
+		{name: "default", query: "", want: defaultOrder},
+		{name: "field only", query: "orderBy=name", want: order.NewBy("name", order.ASC)},
+		{name: "field and direction", query: "orderBy=name,DESC", want: order.NewBy("name", order.DESC)},
+		{name: "spaces", query: "orderBy=%20name%20,%20DESC%20", want: order.NewBy("name", order.DESC)},
+		{name: "mapped field", query: "orderBy=user_id,ASC", want: order.NewBy("id", order.ASC)},
+		{name: "unknown field", query: "orderBy=password,ASC", wantErr: true},
+		{name: "store field not exposed", query: "orderBy=id,ASC", wantErr: true},
+		{name: "bad direction", query: "orderBy=name,UP", wantErr: true},
+		{name: "too many parts", query: "orderBy=name,ASC,DESC", wantErr: true},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/users?"+tst.query, nil)
+
+			got, err := order.Parse(r, fieldMappings, defaultOrder)
+			if tst.wantErr {
+				if !validate.IsFieldErrors(err) {
+					t.Fatalf("Should get a field error: got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Should be able to parse the order: %s", err)
+			}
+			if got != tst.want {
+				t.Fatalf("Should get the right order: got %+v, want %+v", got, tst.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package sqldb provides support for access the database.
+// Every store talks to Postgres through GORM, and GORM or the driver underneath have their own errors for the same
+// problems. The store packages translate those into the errors in here, so a core package can check what happened
+// without knowing anything about GORM or Postgres.
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrDBNotFound        = errors.New("not found")
+	ErrDBDuplicatedEntry = errors.New("duplicated entry")
+)
+
+// Config is the required properties to use the database.
+type Config struct {
+	User         string
+	Password     string
+	Host         string
+	Port         int
+	Name         string
+	MaxIdleConns int
+	MaxOpenConns int
+	DisableTLS   bool
+}
+
+// Open knows how to open a database connection based on the configuration.
+// Opening doesn't mean the database is there, we don't ping on open so the service can start before the database is
+// up, the readiness check is what tells the world if we can talk to it.
+func Open(cfg Config) (*gorm.DB, error) {
+	sslMode := "require"
+	if cfg.DisableTLS {
+		sslMode = "disable"
+	}
+
+	dsn := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host,
+		cfg.Port,
+		cfg.User,
+		cfg.Password,
+		cfg.Name,
+		sslMode,
+	)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:               logger.Default.LogMode(logger.Silent),
+		DisableAutomaticPing: true,
+		TranslateError:       true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("getting raw database handle: %w", err)
+	}
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	// Zero means unlimited – matches sql.DB behavior
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	return db, nil
+}
+
+// Close closes the connections underneath the GORM value.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("getting raw database handle: %w", err)
+	}
+
+	return sqlDB.Close()
+}
+
+// StatusCheck returns nil if it can successfully talk to the database. It returns a non-nil error otherwise.
+func StatusCheck(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("getting raw database handle: %w", err)
+	}
+
+	// If the caller doesn't give us a deadline set 1 second.
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Second)
+		defer cancel()
+	}
+
+	// We keep pinging with a small backoff until the deadline, the database could be in the middle of starting.
+	for attempts := 1; ; attempts++ {
+		if err := sqlDB.PingContext(ctx); err == nil {
+			break
+		}
+		time.Sleep(time.Duration(attempts) * 100 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	// Run a simple query to determine connectivity.
+	var tmp bool
+	return sqlDB.QueryRowContext(ctx, "SELECT true").Scan(&tmp)
+}
+
+// uniqueViolation is the Postgres error code for a unique constraint violation.
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const uniqueViolation = "23505"
+
+// TranslateError converts an error coming back from GORM into one of the sentinel errors of this package when it
+// knows what the error means, otherwise the error is returned as is.
+// We check both drivers, the service is using pgx but the migration tooling opens the connection with lib/pq.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDBNotFound
+	}
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDBDuplicatedEntry
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrDBDuplicatedEntry
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDBDuplicatedEntry
+	}
+
+	return err
+}
@@ -7,10 +7,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -25,15 +27,8 @@ var ErrForbidden = errors.New("attempted action is not allowed")
 type Claims struct {
 	// The subject in the claims will be the uuid for the user
 	jwt.RegisteredClaims
-	Roles []string `json:"roles"`
-}
-
-// A RuleFn returns nil if authorization passes, or an error if it fails.
-type ruleFn func(claims Claims) error
-
-// ruleFns maps rule names (as passed into Authorize) to their Go implementations.
-var ruleFns = map[string]ruleFn{
-	RuleAdminOnly: adminOnly,
+	Roles  []string `json:"roles"`
+	Scopes []string `json:"scopes,omitempty"`
 }
 
 // KeyLookup declares a method set of behavior for looking up private and public keys for JWT use.
@@ -44,14 +39,59 @@ type KeyLookup interface {
 	PublicKey(kid string) (key string, err error)
 }
 
+// splitKeyLookup signs with the private keys of one KeyLookup and verifies with the public keys of another.
+type splitKeyLookup struct {
+	private KeyLookup
+	public  KeyLookup
+}
+
+// NewSplitKeyLookup constructs a KeyLookup that takes private keys from one lookup and public keys from another, e.g.
+// tokens are signed with the keys on disk but verified against the JWKS of the service that issues them.
+func NewSplitKeyLookup(private KeyLookup, public KeyLookup) KeyLookup {
+	return splitKeyLookup{
+		private: private,
+		public:  public,
+	}
+}
+
+// PrivateKey implements the KeyLookup interface.
+func (l splitKeyLookup) PrivateKey(kid string) (string, error) {
+	return l.private.PrivateKey(kid)
+}
+
+// PublicKey implements the KeyLookup interface.
+func (l splitKeyLookup) PublicKey(kid string) (string, error) {
+	return l.public.PublicKey(kid)
+}
+
+// UserLookup declares the behavior auth needs to check a user still exists and is enabled.
+// It is optional, when it is not provided the check is skipped and a token is good until it expires.
+type UserLookup interface {
+	QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error)
+}
+
+// RevocationLookup declares the behavior auth needs to load the access tokens that were revoked before they expired.
+// It is optional, when it is not provided tokens can't be revoked.
+type RevocationLookup interface {
+	QueryRevocations(ctx context.Context) ([]session.Revocation, error)
+}
+
 // Config represents information required to initialize auth.
 // In the config we are asking for a logger, we can do that because we are in the business layer.
 // We have the implementation of the KeyLookup interface and the Issuer name.
 // The "Config" carries dependency injection that will come from the App layer
 type Config struct {
-	Log       *zap.SugaredLogger
-	KeyLookup KeyLookup
-	Issuer    string
+	Log        *zap.SugaredLogger
+	KeyLookup  KeyLookup
+	Issuer     string
+	UserLookup UserLookup
+	// How long the enabled state of a user is trusted before we ask the UserLookup again, defaults to 30 seconds.
+	UserCacheTTL time.Duration
+	// How long a public key is trusted before we ask the KeyLookup again, defaults to 5 minutes.
+	KeyCacheTTL      time.Duration
+	RevocationLookup RevocationLookup
+	// How often the revoked tokens are loaded from the RevocationLookup, defaults to 30 seconds.
+	RevocationRefresh time.Duration
 }
 
 // Auth is used to authenticate clients.
@@ -59,45 +99,94 @@ type Config struct {
 type Auth struct {
 	log       *zap.SugaredLogger
 	keyLookup KeyLookup
-	method    jwt.SigningMethod
-	parser    *jwt.Parser
-	issuer    string
+	// parser is only used to read the kid out of a token, the signature is checked with the parser of the kid.
+	parser *jwt.Parser
+	issuer string
 	// this basic caching is for saving the keys so we don't have to do the network call to get the key every time
-	// In reality, you need to implement something to periodically fetch the keys back again.
-	mu    sync.RWMutex
-	cache map[string]string
+	// Every entry expires, so a key that was removed or replaced behind the KeyLookup stops being used.
+	mu          sync.RWMutex
+	cache       map[string]cachedKey
+	keyCacheTTL time.Duration
+	// The parsed private keys, so signing a token doesn't parse the PEM again.
+	signers map[string]cachedSigner
+	// The enabled state of the users is cached, so the hot path doesn't hit the DB on every request.
+	userLookup UserLookup
+	users      *userCache
+	// The revoked tokens are kept in memory and loaded again every so often.
+	revocationLookup RevocationLookup
+	revoked          *revocationList
+	// The rules are looked up by name on every request, they can be registered while the service is running.
+	rulesMu sync.RWMutex
+	rules   map[string]Predicate
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(cfg Config) (*Auth, error) {
+	userCacheTTL := cfg.UserCacheTTL
+	if userCacheTTL <= 0 {
+		userCacheTTL = 30 * time.Second
+	}
+
+	keyCacheTTL := cfg.KeyCacheTTL
+	if keyCacheTTL <= 0 {
+		keyCacheTTL = 5 * time.Minute
+	}
+
+	revocationRefresh := cfg.RevocationRefresh
+	if revocationRefresh <= 0 {
+		revocationRefresh = 30 * time.Second
+	}
+
 	a := Auth{
-		keyLookup: cfg.KeyLookup,
-		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
-		parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
-		issuer:    cfg.Issuer,
-		cache:     make(map[string]string),
+		log:         cfg.Log,
+		keyLookup:   cfg.KeyLookup,
+		parser:      jwt.NewParser(jwt.WithValidMethods(supportedMethods)),
+		issuer:      cfg.Issuer,
+		cache:       make(map[string]cachedKey),
+		keyCacheTTL: keyCacheTTL,
+		signers:     make(map[string]cachedSigner),
+		// The user cache is only used when there is a lookup to fill it.
+		userLookup: cfg.UserLookup,
+		users:      newUserCache(userCacheTTL),
+		// The list is loaded on the first token we see.
+		revocationLookup: cfg.RevocationLookup,
+		revoked:          newRevocationList(revocationRefresh),
+		rules:            defaultRules(),
 	}
 
 	return &a, nil
 }
 
-// GenerateToken generates a signed JWT token string representing the user Claims.
-func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
-	// Creating a token using claims with kid
-	token := jwt.NewWithClaims(a.method, claims)
-	token.Header["kid"] = kid
+// Issuer returns the issuer this Auth generates and accepts tokens for.
+func (a *Auth) Issuer() string {
+	return a.issuer
+}
 
-	privateKeyPEM, err := a.keyLookup.PrivateKey(kid)
-	if err != nil {
-		return "", fmt.Errorf("private key: %w", err)
+// DropKeys removes the kids from the key caches, it is called when keys are rotated so a key that was replaced or
+// removed isn't trusted until its cache entry expires.
+func (a *Auth) DropKeys(kids ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, kid := range kids {
+		delete(a.cache, kid)
+		delete(a.signers, kid)
 	}
+}
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
+// GenerateToken generates a signed JWT token string representing the user Claims.
+// The algorithm is the one that goes with the key of the kid.
+func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
+	signer, err := a.signerLookup(kid)
 	if err != nil {
-		return "", fmt.Errorf("parsing private pem: %w", err)
+		return "", err
 	}
 
-	str, err := token.SignedString(privateKey)
+	// Creating a token using claims with kid
+	token := jwt.NewWithClaims(signer.method, claims)
+	token.Header["kid"] = kid
+
+	str, err := token.SignedString(signer.key)
 	if err != nil {
 		return "", fmt.Errorf("signing token: %w", err)
 	}
@@ -134,18 +223,18 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 	}
 
 	// 5. Gets public key
-	pem, err := a.publicKeyLookup(kid)
+	key, err := a.publicKeyLookup(kid)
 	if err != nil {
 		return Claims{}, fmt.Errorf("failed to fetch public key: %w", err)
 	}
 
 	keyFunc := func(t *jwt.Token) (interface{}, error) {
-		// Parse the PEM into an *rsa.PublicKey
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(pem))
+		return key.key, nil
 	}
 
-	// 6. Validate token
-	_, err = a.parser.ParseWithClaims(parts[1], &claims, keyFunc)
+	// 6. Validate token, the parser of the kid only accepts the algorithm of its key. A token for an RSA kid with
+	// "alg" set to anything else is rejected before the key is used.
+	_, err = key.parser.ParseWithClaims(parts[1], &claims, keyFunc)
 
 	if err != nil {
 		return Claims{}, fmt.Errorf("authentication failed : %w", err)
@@ -156,6 +245,17 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 		return Claims{}, fmt.Errorf("authentication failed: wrong issuer")
 	}
 
+	// The parser only checks exp when it is there, a token without it would be good forever and couldn't be revoked
+	// since the revocation has to know until when to remember it.
+	if claims.ExpiresAt == nil {
+		return Claims{}, fmt.Errorf("authentication failed: token has no expiry")
+	}
+
+	// 8. Check the token wasn't revoked, e.g. the user logged out.
+	if err := a.isRevoked(ctx, claims); err != nil {
+		return Claims{}, fmt.Errorf("token revoked : %w", err)
+	}
+
 	// Check the database for this user to verify they are still enabled.
 	if err := a.isUserEnabled(ctx, claims); err != nil {
 		return Claims{}, fmt.Errorf("user not enabled : %w", err)
@@ -164,19 +264,38 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 	return claims, nil
 }
 
-// Authorize attempts to authorize the user with the provided input roles, if
-// none of the input roles are within the user's claims, we return an error
-// otherwise the user is authorized.
-func (a *Auth) Authorize(claims Claims, userID uuid.UUID, rule string) error {
+// RegisterRule adds the rule under the name, replacing a rule with the same name if there is one.
+func (a *Auth) RegisterRule(name string, p Predicate) {
+	a.rulesMu.Lock()
+	defer a.rulesMu.Unlock()
+
+	a.rules[name] = p
+}
+
+// HasRule reports whether a rule is registered under the name.
+func (a *Auth) HasRule(name string) bool {
+	a.rulesMu.RLock()
+	defer a.rulesMu.RUnlock()
+
+	_, ok := a.rules[name]
+	return ok
+}
+
+// Authorize evaluates the named rule against the input. When the rule denies access the error is a *Denial saying
+// which predicate failed, any other error means the rule couldn't be evaluated, e.g. the resource owner wasn't found.
+func (a *Auth) Authorize(ctx context.Context, rule string, in Input) error {
+	a.rulesMu.RLock()
+	p, ok := a.rules[rule]
+	a.rulesMu.RUnlock()
 
-	fn, ok := ruleFns[rule]
 	if !ok {
-		return fmt.Errorf("authorization rule not found")
+		return NewAuthError("authorization rule %q not found", rule)
 	}
 
-	// Executing the rule function
-	if err := fn(claims); err != nil {
-		return err
+	in.ResourceOwner = onceOwner(in.ResourceOwner)
+
+	if err := p.fn(ctx, in); err != nil {
+		return fmt.Errorf("rule[%s]: %w", rule, err)
 	}
 
 	return nil
@@ -185,47 +304,94 @@ func (a *Auth) Authorize(claims Claims, userID uuid.UUID, rule string) error {
 // =============================================================================
 // This barer for unexported functions
 
-// publicKeyLookup performs a lookup for the public pem for the specified kid.
-func (a *Auth) publicKeyLookup(kid string) (string, error) {
+// publicKeyLookup performs a lookup for the public key for the specified kid.
+func (a *Auth) publicKeyLookup(kid string) (cachedKey, error) {
 	// Bill wanted to make this more simpler, so he made a literal function and executed it right away.
-	pem, err := func() (string, error) {
+	key, err := func() (cachedKey, error) {
 		a.mu.RLock()
 		defer a.mu.RUnlock()
-		// This is a cache lookup for the pem key
-		pem, exists := a.cache[kid]
-		if !exists {
-			return "", errors.New("not found")
+		// This is a cache lookup for the key
+		key, exists := a.cache[kid]
+		if !exists || time.Now().After(key.expires) {
+			return cachedKey{}, errors.New("not found")
 		}
-		return pem, nil
+		return key, nil
 	}()
 
 	if err == nil {
-		return pem, nil
+		return key, nil
 	}
 	// if it wasn't there we will make the network call "assuming that is on another service"
-	pem, err = a.keyLookup.PublicKey(kid)
+	pem, err := a.keyLookup.PublicKey(kid)
+	if err != nil {
+		return cachedKey{}, fmt.Errorf("fetching public key: %w", err)
+	}
 
+	// The key is parsed once here and not on every request.
+	publicKey, method, err := publicKeyFromPEM(pem)
 	if err != nil {
-		return "", fmt.Errorf("fetching public key: %w", err)
+		return cachedKey{}, fmt.Errorf("parsing public pem: %w", err)
+	}
+
+	key = cachedKey{
+		key:     publicKey,
+		parser:  jwt.NewParser(jwt.WithValidMethods([]string{method.Alg()})),
+		expires: time.Now().Add(a.keyCacheTTL),
 	}
+
 	// Store in the cache
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	a.cache[kid] = pem
-	return pem, nil
+	a.cache[kid] = key
+	return key, nil
 }
 
-func adminOnly(claims Claims) error {
-	ok := slices.Contains(claims.Roles, "ADMIN")
-	if !ok {
-		return ErrForbidden
+// signerLookup returns the parsed private key for the kid.
+// The KeyLookup is still asked on every call, that is what tells us the key was retired or replaced, only the parsing
+// is skipped when the PEM is the one we parsed before.
+func (a *Auth) signerLookup(kid string) (cachedSigner, error) {
+	pem, err := a.keyLookup.PrivateKey(kid)
+	if err != nil {
+		return cachedSigner{}, fmt.Errorf("private key: %w", err)
 	}
-	return nil
+
+	a.mu.RLock()
+	signer, exists := a.signers[kid]
+	a.mu.RUnlock()
+
+	if exists && signer.pem == pem {
+		return signer, nil
+	}
+
+	key, method, err := privateKeyFromPEM(pem)
+	if err != nil {
+		return cachedSigner{}, fmt.Errorf("parsing private pem: %w", err)
+	}
+
+	signer = cachedSigner{
+		pem:    pem,
+		key:    key,
+		method: method,
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.signers[kid] = signer
+
+	return signer, nil
 }
 
-// isUserEnabled hits the database and checks the user is not disabled. If the
-// no database connection was provided, this check is skipped.
-func (a *Auth) isUserEnabled(ctx context.Context, claims Claims) error {
+// cachedSigner is a parsed private key with the PEM it was parsed from and the method it signs with.
+type cachedSigner struct {
+	pem    string
+	key    any
+	method jwt.SigningMethod
+}
 
-	return nil
+// cachedKey is a parsed public key in the cache with the parser for its algorithm and the time we have to ask the
+// KeyLookup for it again.
+type cachedKey struct {
+	key     any
+	parser  *jwt.Parser
+	expires time.Time
 }
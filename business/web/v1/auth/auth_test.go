@@ -0,0 +1,278 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/pemkey"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// keyLookup is a KeyLookup the test can swap keys in.
+type keyLookup struct {
+	mu      sync.Mutex
+	private map[string]string
+}
+
+// set swaps a new Ed25519 key in for the kid.
+func (kl *keyLookup) set(t *testing.T, kid string) {
+	t.Helper()
+
+	_, pk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+	kl.setKey(t, kid, pk)
+}
+
+// setKey swaps the key in for the kid.
+func (kl *keyLookup) setKey(t *testing.T, kid string, pk crypto.Signer) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the key: %s", err)
+	}
+
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	kl.private[kid] = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func (kl *keyLookup) remove(kid string) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	delete(kl.private, kid)
+}
+
+func (kl *keyLookup) PrivateKey(kid string) (string, error) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	key, exists := kl.private[kid]
+	if !exists {
+		return "", errors.New("kid not found")
+	}
+	return key, nil
+}
+
+func (kl *keyLookup) PublicKey(kid string) (string, error) {
+	pk, err := kl.PrivateKey(kid)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := pemkey.ParsePrivate([]byte(pk))
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func newClaims() auth.Claims {
+	now := time.Now()
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+			Issuer:    "test",
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+}
+
+func Test_GenerateTokenFollowsKeyChanges(t *testing.T) {
+	kl := keyLookup{private: make(map[string]string)}
+	kl.set(t, "kid-1")
+
+	a, err := auth.New(auth.Config{KeyLookup: &kl, Issuer: "test"})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	authenticate := func(token string) error {
+		a.DropKeys("kid-1")
+		_, err := a.Authenticate(context.Background(), "Bearer "+token)
+		return err
+	}
+
+	token, err := a.GenerateToken("kid-1", newClaims())
+	if err != nil {
+		t.Fatalf("Should be able to sign: %s", err)
+	}
+	if err := authenticate(token); err != nil {
+		t.Fatalf("Should verify the token: %s", err)
+	}
+
+	// The file behind the kid is replaced, the next token has to be signed with the new key even though the old one
+	// was parsed and cached.
+	kl.set(t, "kid-1")
+
+	token, err = a.GenerateToken("kid-1", newClaims())
+	if err != nil {
+		t.Fatalf("Should be able to sign with the replaced key: %s", err)
+	}
+	if err := authenticate(token); err != nil {
+		t.Fatalf("Should verify a token signed with the replaced key: %s", err)
+	}
+
+	// A key that can't be used for signing anymore is not used from the cache either.
+	kl.remove("kid-1")
+
+	if _, err := a.GenerateToken("kid-1", newClaims()); err == nil {
+		t.Fatal("Should not sign with a key the lookup doesn't hand out anymore")
+	}
+}
+
+func Test_AuthenticateRequiresExpiry(t *testing.T) {
+	kl := keyLookup{private: make(map[string]string)}
+	kl.set(t, "kid-1")
+
+	a, err := auth.New(auth.Config{KeyLookup: &kl, Issuer: "test"})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	claims := newClaims()
+	claims.ExpiresAt = nil
+
+	token, err := a.GenerateToken("kid-1", claims)
+	if err != nil {
+		t.Fatalf("Should be able to sign: %s", err)
+	}
+
+	if _, err := a.Authenticate(context.Background(), "Bearer "+token); err == nil {
+		t.Fatal("Should reject a token without an expiry")
+	}
+}
+
+func Test_GenerateTokenAlgorithms(t *testing.T) {
+	tt := []struct {
+		name    string
+		key     func() (crypto.Signer, error)
+		wantAlg string
+	}{
+		{name: "rsa", key: func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) }, wantAlg: "RS256"},
+		{name: "p256", key: func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) }, wantAlg: "ES256"},
+		{name: "p384", key: func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) }, wantAlg: "ES384"},
+		{name: "ed25519", key: func() (crypto.Signer, error) {
+			_, pk, err := ed25519.GenerateKey(rand.Reader)
+			return pk, err
+		}, wantAlg: "EdDSA"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			pk, err := tst.key()
+			if err != nil {
+				t.Fatalf("Should be able to generate a key: %s", err)
+			}
+
+			kl := keyLookup{private: make(map[string]string)}
+			kl.setKey(t, "kid-1", pk)
+
+			a, err := auth.New(auth.Config{KeyLookup: &kl, Issuer: "test"})
+			if err != nil {
+				t.Fatalf("Should be able to construct auth: %s", err)
+			}
+
+			token, err := a.GenerateToken("kid-1", newClaims())
+			if err != nil {
+				t.Fatalf("Should be able to sign: %s", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+			if err != nil {
+				t.Fatalf("Should be able to read the token: %s", err)
+			}
+			if alg := parsed.Header["alg"]; alg != tst.wantAlg {
+				t.Errorf("Should sign with %s, got %v", tst.wantAlg, alg)
+			}
+
+			claims, err := a.Authenticate(context.Background(), "Bearer "+token)
+			if err != nil {
+				t.Fatalf("Should verify the token: %s", err)
+			}
+			if claims.Subject != newClaims().Subject {
+				t.Errorf("Should return the claims of the token, got subject %q", claims.Subject)
+			}
+		})
+	}
+}
+
+func Test_AuthenticateRejectsAlgMismatch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	kl := keyLookup{private: make(map[string]string)}
+	kl.setKey(t, "kid-rsa", rsaKey)
+	kl.setKey(t, "kid-ec", ecKey)
+
+	rsaPublic, err := kl.PublicKey("kid-rsa")
+	if err != nil {
+		t.Fatalf("Should be able to get the public key: %s", err)
+	}
+
+	a, err := auth.New(auth.Config{KeyLookup: &kl, Issuer: "test"})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	// Every token is signed correctly for the alg it claims, only the pinning of the kid to its algorithm can stop it.
+	tt := []struct {
+		name   string
+		kid    string
+		method jwt.SigningMethod
+		key    any
+	}{
+		{name: "same rsa key, RS384", kid: "kid-rsa", method: jwt.SigningMethodRS384, key: rsaKey},
+		{name: "same rsa key, PS256", kid: "kid-rsa", method: jwt.SigningMethodPS256, key: rsaKey},
+		{name: "public key as hmac secret", kid: "kid-rsa", method: jwt.SigningMethodHS256, key: []byte(rsaPublic)},
+		{name: "ec signature on an rsa kid", kid: "kid-rsa", method: jwt.SigningMethodES256, key: ecKey},
+		{name: "rsa signature on an ec kid", kid: "kid-ec", method: jwt.SigningMethodRS256, key: rsaKey},
+		{name: "none", kid: "kid-rsa", method: jwt.SigningMethodNone, key: jwt.UnsafeAllowNoneSignatureType},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(tst.method, newClaims())
+			token.Header["kid"] = tst.kid
+
+			str, err := token.SignedString(tst.key)
+			if err != nil {
+				t.Fatalf("Should be able to sign: %s", err)
+			}
+
+			_, err = a.Authenticate(context.Background(), "Bearer "+str)
+			if err == nil {
+				t.Fatalf("Should reject a %s token for %s", tst.method.Alg(), tst.kid)
+			}
+			if !strings.Contains(err.Error(), "signing method") {
+				t.Errorf("Should reject the token for its algorithm, got %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/google/uuid"
+)
+
+// isUserEnabled checks the user in the claims still exists and is not disabled. If no UserLookup was provided, this
+// check is skipped.
+// The answer is cached for a short time, a disabled user is locked out within that time instead of when their token
+// expires. A lookup that fails for any other reason fails the authentication and is not cached.
+func (a *Auth) isUserEnabled(ctx context.Context, claims Claims) error {
+	if a.userLookup == nil {
+		return nil
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return NewAuthError("subject is not a valid user id: %s", claims.Subject)
+	}
+
+	enabled, found := a.users.get(userID)
+	if !found {
+		usr, err := a.userLookup.QueryByID(ctx, userID)
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			enabled = false
+		case err != nil:
+			return fmt.Errorf("query user: %w", err)
+		default:
+			enabled = usr.Enabled
+		}
+
+		a.users.set(userID, enabled)
+	}
+
+	if !enabled {
+		return NewAuthError("user %s is disabled or deleted", userID)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// userCache keeps the enabled state of users for a short TTL.
+type userCache struct {
+	ttl time.Duration
+
+	mu        sync.RWMutex
+	users     map[uuid.UUID]userCacheEntry
+	lastSweep time.Time
+}
+
+type userCacheEntry struct {
+	enabled bool
+	expires time.Time
+}
+
+func newUserCache(ttl time.Duration) *userCache {
+	return &userCache{
+		ttl:       ttl,
+		users:     make(map[uuid.UUID]userCacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// get returns the cached enabled state of the user, found is false when there is nothing or it expired.
+func (uc *userCache) get(userID uuid.UUID) (enabled bool, found bool) {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+
+	entry, exists := uc.users[userID]
+	if !exists || time.Now().After(entry.expires) {
+		return false, false
+	}
+
+	return entry.enabled, true
+}
+
+// set caches the enabled state of the user. Once every TTL we drop the expired entries, so users that stopped making
+// requests don't stay in memory forever.
+func (uc *userCache) set(userID uuid.UUID, enabled bool) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(uc.lastSweep) > uc.ttl {
+		for id, entry := range uc.users {
+			if now.After(entry.expires) {
+				delete(uc.users, id)
+			}
+		}
+		uc.lastSweep = now
+	}
+
+	uc.users[userID] = userCacheEntry{
+		enabled: enabled,
+		expires: now.Add(uc.ttl),
+	}
+}
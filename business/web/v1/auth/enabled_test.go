@@ -0,0 +1,175 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/user"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/google/uuid"
+)
+
+// userLookup is a UserLookup that counts the queries, err is returned instead of the user when it is set.
+type userLookup struct {
+	mu      sync.Mutex
+	users   map[uuid.UUID]user.User
+	err     error
+	queries int
+}
+
+func (ul *userLookup) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	ul.queries++
+
+	if ul.err != nil {
+		return user.User{}, ul.err
+	}
+
+	usr, exists := ul.users[userID]
+	if !exists {
+		return user.User{}, user.ErrNotFound
+	}
+	return usr, nil
+}
+
+func (ul *userLookup) setEnabled(userID uuid.UUID, enabled bool) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	ul.users[userID] = user.User{ID: userID, Enabled: enabled}
+}
+
+func (ul *userLookup) setErr(err error) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	ul.err = err
+}
+
+func (ul *userLookup) count() int {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	return ul.queries
+}
+
+// newEnabledAuth constructs an Auth with the user lookup and a token for the subject of newClaims.
+func newEnabledAuth(t *testing.T, ul *userLookup, ttl time.Duration) (*auth.Auth, uuid.UUID, string) {
+	t.Helper()
+
+	kl := keyLookup{private: make(map[string]string)}
+	kl.set(t, "kid-1")
+
+	a, err := auth.New(auth.Config{KeyLookup: &kl, Issuer: "test", UserLookup: ul, UserCacheTTL: ttl})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	claims := newClaims()
+
+	token, err := a.GenerateToken("kid-1", claims)
+	if err != nil {
+		t.Fatalf("Should be able to sign: %s", err)
+	}
+
+	return a, uuid.MustParse(claims.Subject), "Bearer " + token
+}
+
+func Test_AuthenticateRejectsDisabledUsers(t *testing.T) {
+	tt := []struct {
+		name string
+		// enabled is nil when the user doesn't exist.
+		enabled *bool
+		wantErr bool
+	}{
+		{name: "enabled", enabled: ptr(true)},
+		{name: "disabled", enabled: ptr(false), wantErr: true},
+		{name: "deleted", wantErr: true},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			ul := userLookup{users: make(map[uuid.UUID]user.User)}
+			a, userID, bearer := newEnabledAuth(t, &ul, time.Minute)
+
+			if tst.enabled != nil {
+				ul.setEnabled(userID, *tst.enabled)
+			}
+
+			_, err := a.Authenticate(context.Background(), bearer)
+
+			switch {
+			case tst.wantErr:
+				if !auth.IsAuthError(err) {
+					t.Errorf("Should fail with an auth error, got %v", err)
+				}
+			case err != nil:
+				t.Errorf("Should authenticate: %s", err)
+			}
+		})
+	}
+}
+
+func Test_AuthenticateCachesEnabledState(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+
+	ul := userLookup{users: make(map[uuid.UUID]user.User)}
+	a, userID, bearer := newEnabledAuth(t, &ul, ttl)
+	ul.setEnabled(userID, true)
+
+	if _, err := a.Authenticate(context.Background(), bearer); err != nil {
+		t.Fatalf("Should authenticate: %s", err)
+	}
+
+	// Disabled behind the cache, the cached answer is trusted until the TTL is up.
+	ul.setEnabled(userID, false)
+
+	if _, err := a.Authenticate(context.Background(), bearer); err != nil {
+		t.Fatalf("Should authenticate from the cache: %s", err)
+	}
+	if n := ul.count(); n != 1 {
+		t.Fatalf("Should query the user once within the TTL, got %d", n)
+	}
+
+	time.Sleep(ttl + 20*time.Millisecond)
+
+	if _, err := a.Authenticate(context.Background(), bearer); !auth.IsAuthError(err) {
+		t.Fatalf("Should reject the disabled user once the cache expired, got %v", err)
+	}
+	if n := ul.count(); n != 2 {
+		t.Fatalf("Should query the user again after the TTL, got %d", n)
+	}
+}
+
+func Test_AuthenticateDoesNotCacheLookupErrors(t *testing.T) {
+	errDB := errors.New("database is down")
+
+	ul := userLookup{users: make(map[uuid.UUID]user.User)}
+	a, userID, bearer := newEnabledAuth(t, &ul, time.Minute)
+	ul.setEnabled(userID, true)
+	ul.setErr(errDB)
+
+	_, err := a.Authenticate(context.Background(), bearer)
+	if !errors.Is(err, errDB) {
+		t.Fatalf("Should fail with the lookup error, got %v", err)
+	}
+	if auth.IsAuthError(err) {
+		t.Fatalf("Should not turn a lookup failure into an auth error: %s", err)
+	}
+
+	// The database is back, the failure wasn't remembered so the user gets in right away.
+	ul.setErr(nil)
+
+	if _, err := a.Authenticate(context.Background(), bearer); err != nil {
+		t.Fatalf("Should authenticate once the lookup works again: %s", err)
+	}
+	if n := ul.count(); n != 2 {
+		t.Fatalf("Should query the user again after a failed lookup, got %d", n)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
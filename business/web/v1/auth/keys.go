@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/pemkey"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The KeyLookup hands us PEM strings, what algorithm a kid signs with follows from the kind of key in the PEM. RSA
+// keys sign with RS256, EC keys with ES256 or ES384 depending on the curve and Ed25519 keys with EdDSA.
+
+// supportedMethods are all the algorithms we accept, a single kid only ever accepts the one that goes with its key.
+var supportedMethods = []string{
+	jwt.SigningMethodRS256.Name,
+	jwt.SigningMethodES256.Name,
+	jwt.SigningMethodES384.Name,
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+// privateKeyFromPEM parses the private key and returns it with the method it signs with.
+func privateKeyFromPEM(data string) (any, jwt.SigningMethod, error) {
+	key, err := pemkey.ParsePrivate([]byte(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method, err := signingMethod(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, method, nil
+}
+
+// publicKeyFromPEM parses the public key and returns it with the method it verifies.
+func publicKeyFromPEM(data string) (any, jwt.SigningMethod, error) {
+	key, err := pemkey.ParsePublic([]byte(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method, err := signingMethod(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, method, nil
+}
+
+// signingMethod returns the method for a private or a public key.
+func signingMethod(key any) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+
+	case *ecdsa.PrivateKey:
+		return ecdsaMethod(k.Curve)
+
+	case *ecdsa.PublicKey:
+		return ecdsaMethod(k.Curve)
+
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+func ecdsaMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	}
+
+	return nil, fmt.Errorf("unsupported curve %s", curve.Params().Name)
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Instead of one function per rule, rules are built out of small predicates that compose. A rule like "admin or the
+// user itself" is AnyOf(HasRole("ADMIN"), IsSubject()), and a new rule is a new composition registered by name, not
+// a new function.
+
+// ResourceOwnerFunc resolves the owner of the resource the request is acting on. It is only called when a predicate
+// needs it, and at most once per request.
+type ResourceOwnerFunc func(ctx context.Context) (uuid.UUID, error)
+
+// Input is what the predicates get to make their decision.
+type Input struct {
+	Claims Claims
+	// UserID is the user the request is acting on, the zero value when the route isn't about a specific user.
+	UserID uuid.UUID
+	// ResourceOwner is nil when the route has no resource that belongs to somebody.
+	ResourceOwner ResourceOwnerFunc
+}
+
+// Predicate is a named check against the Input. The name is what gets reported when it denies access.
+type Predicate struct {
+	name string
+	fn   func(ctx context.Context, in Input) error
+}
+
+// Name returns the name of the predicate, e.g. "AnyOf(HasRole(ADMIN), IsSubject)".
+func (p Predicate) Name() string {
+	return p.name
+}
+
+// =============================================================================
+
+// Denial is returned when a predicate denies access, it carries which predicate failed and why so a denied request
+// can be debugged from the logs. It wraps ErrForbidden.
+type Denial struct {
+	Predicate string
+	Reason    string
+}
+
+// Error implements the error interface.
+func (d *Denial) Error() string {
+	return fmt.Sprintf("%s: %s", d.Predicate, d.Reason)
+}
+
+// Unwrap lets errors.Is(err, ErrForbidden) work on a Denial.
+func (d *Denial) Unwrap() error {
+	return ErrForbidden
+}
+
+// IsDenial checks if an error of type Denial exists.
+func IsDenial(err error) bool {
+	var d *Denial
+	return errors.As(err, &d)
+}
+
+func deny(predicate string, format string, args ...any) error {
+	return &Denial{
+		Predicate: predicate,
+		Reason:    fmt.Sprintf(format, args...),
+	}
+}
+
+// =============================================================================
+
+// HasRole passes when the claims carry the role.
+func HasRole(role string) Predicate {
+	name := fmt.Sprintf("HasRole(%s)", role)
+
+	fn := func(ctx context.Context, in Input) error {
+		if !slices.Contains(in.Claims.Roles, role) {
+			return deny(name, "roles %v", in.Claims.Roles)
+		}
+		return nil
+	}
+
+	return Predicate{name: name, fn: fn}
+}
+
+// HasScope passes when the claims carry the scope.
+func HasScope(scope string) Predicate {
+	name := fmt.Sprintf("HasScope(%s)", scope)
+
+	fn := func(ctx context.Context, in Input) error {
+		if !slices.Contains(in.Claims.Scopes, scope) {
+			return deny(name, "scopes %v", in.Claims.Scopes)
+		}
+		return nil
+	}
+
+	return Predicate{name: name, fn: fn}
+}
+
+// IsSubject passes when the user the request is acting on is the subject of the claims.
+func IsSubject() Predicate {
+	const name = "IsSubject"
+
+	fn := func(ctx context.Context, in Input) error {
+		if in.UserID == uuid.Nil {
+			return deny(name, "no user in the request")
+		}
+		if in.Claims.Subject != in.UserID.String() {
+			return deny(name, "subject %s is not user %s", in.Claims.Subject, in.UserID)
+		}
+		return nil
+	}
+
+	return Predicate{name: name, fn: fn}
+}
+
+// OwnsResource passes when the subject of the claims owns the resource the request is acting on.
+// A failure to resolve the owner is not a denial, it is returned as is so the caller can respond with it.
+func OwnsResource() Predicate {
+	const name = "OwnsResource"
+
+	fn := func(ctx context.Context, in Input) error {
+		if in.ResourceOwner == nil {
+			return deny(name, "no resource in the request")
+		}
+
+		ownerID, err := in.ResourceOwner(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: resolving owner: %w", name, err)
+		}
+
+		if in.Claims.Subject != ownerID.String() {
+			return deny(name, "subject %s is not owner %s", in.Claims.Subject, ownerID)
+		}
+		return nil
+	}
+
+	return Predicate{name: name, fn: fn}
+}
+
+// AnyOf passes when at least one of the predicates passes. When all of them deny, the denial reports every reason.
+func AnyOf(predicates ...Predicate) Predicate {
+	name := "AnyOf(" + joinNames(predicates) + ")"
+
+	fn := func(ctx context.Context, in Input) error {
+		reasons := make([]string, 0, len(predicates))
+		for _, p := range predicates {
+			err := p.fn(ctx, in)
+			switch {
+			case err == nil:
+				return nil
+			case !IsDenial(err):
+				return err
+			}
+			reasons = append(reasons, err.Error())
+		}
+
+		return deny(name, "%s", strings.Join(reasons, "; "))
+	}
+
+	return Predicate{name: name, fn: fn}
+}
+
+// AllOf passes when every predicate passes. The denial reports the first predicate that failed.
+func AllOf(predicates ...Predicate) Predicate {
+	name := "AllOf(" + joinNames(predicates) + ")"
+
+	fn := func(ctx context.Context, in Input) error {
+		for _, p := range predicates {
+			if err := p.fn(ctx, in); err != nil {
+				if !IsDenial(err) {
+					return err
+				}
+				return deny(name, "%s", err)
+			}
+		}
+
+		return nil
+	}
+
+	return Predicate{name: name, fn: fn}
+}
+
+func joinNames(predicates []Predicate) string {
+	names := make([]string, len(predicates))
+	for i, p := range predicates {
+		names[i] = p.name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// =============================================================================
+
+// onceOwner makes sure the resource owner is resolved once per request, no matter how many predicates ask for it.
+func onceOwner(resolve ResourceOwnerFunc) ResourceOwnerFunc {
+	if resolve == nil {
+		return nil
+	}
+
+	var (
+		once    sync.Once
+		ownerID uuid.UUID
+		err     error
+	)
+
+	return func(ctx context.Context) (uuid.UUID, error) {
+		once.Do(func() {
+			ownerID, err = resolve(ctx)
+		})
+		return ownerID, err
+	}
+}
@@ -0,0 +1,175 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+func Test_Predicates(t *testing.T) {
+	subject := uuid.New()
+	other := uuid.New()
+	errLookup := errors.New("lookup failed")
+
+	owner := func(id uuid.UUID) auth.ResourceOwnerFunc {
+		return func(ctx context.Context) (uuid.UUID, error) { return id, nil }
+	}
+
+	claims := func(roles []string, scopes []string) auth.Claims {
+		return auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: subject.String()},
+			Roles:            roles,
+			Scopes:           scopes,
+		}
+	}
+
+	tt := []struct {
+		name string
+		p    auth.Predicate
+		in   auth.Input
+		// wantDenied is the predicate the denial has to name, empty when the predicate has to pass.
+		wantDenied string
+		wantErr    error
+	}{
+		{name: "has role", p: auth.HasRole(auth.RoleAdmin), in: auth.Input{Claims: claims([]string{auth.RoleAdmin}, nil)}},
+		{name: "missing role", p: auth.HasRole(auth.RoleAdmin), in: auth.Input{Claims: claims([]string{auth.RoleUser}, nil)}, wantDenied: "HasRole(ADMIN)"},
+		{name: "has scope", p: auth.HasScope("links:write"), in: auth.Input{Claims: claims(nil, []string{"links:write"})}},
+		{name: "missing scope", p: auth.HasScope("links:write"), in: auth.Input{Claims: claims(nil, []string{"links:read"})}, wantDenied: "HasScope(links:write)"},
+		{name: "is subject", p: auth.IsSubject(), in: auth.Input{Claims: claims(nil, nil), UserID: subject}},
+		{name: "other user", p: auth.IsSubject(), in: auth.Input{Claims: claims(nil, nil), UserID: other}, wantDenied: "IsSubject"},
+		{name: "no user", p: auth.IsSubject(), in: auth.Input{Claims: claims(nil, nil)}, wantDenied: "IsSubject"},
+		{name: "owns resource", p: auth.OwnsResource(), in: auth.Input{Claims: claims(nil, nil), ResourceOwner: owner(subject)}},
+		{name: "not the owner", p: auth.OwnsResource(), in: auth.Input{Claims: claims(nil, nil), ResourceOwner: owner(other)}, wantDenied: "OwnsResource"},
+		{name: "no resource", p: auth.OwnsResource(), in: auth.Input{Claims: claims(nil, nil)}, wantDenied: "OwnsResource"},
+		{
+			name: "owner lookup fails",
+			p:    auth.OwnsResource(),
+			in: auth.Input{Claims: claims(nil, nil), ResourceOwner: func(ctx context.Context) (uuid.UUID, error) {
+				return uuid.Nil, errLookup
+			}},
+			wantErr: errLookup,
+		},
+		{
+			name: "any of, second passes",
+			p:    auth.AnyOf(auth.HasRole(auth.RoleAdmin), auth.IsSubject()),
+			in:   auth.Input{Claims: claims([]string{auth.RoleUser}, nil), UserID: subject},
+		},
+		{
+			name:       "any of, none pass",
+			p:          auth.AnyOf(auth.HasRole(auth.RoleAdmin), auth.IsSubject()),
+			in:         auth.Input{Claims: claims([]string{auth.RoleUser}, nil), UserID: other},
+			wantDenied: "AnyOf(HasRole(ADMIN), IsSubject)",
+		},
+		{
+			name: "any of, lookup error is not a denial",
+			p:    auth.AnyOf(auth.OwnsResource(), auth.HasRole(auth.RoleAdmin)),
+			in: auth.Input{Claims: claims([]string{auth.RoleAdmin}, nil), ResourceOwner: func(ctx context.Context) (uuid.UUID, error) {
+				return uuid.Nil, errLookup
+			}},
+			wantErr: errLookup,
+		},
+		{
+			name: "all of, all pass",
+			p:    auth.AllOf(auth.HasRole(auth.RoleUser), auth.HasScope("links:write")),
+			in:   auth.Input{Claims: claims([]string{auth.RoleUser}, []string{"links:write"})},
+		},
+		{
+			name:       "all of, one fails",
+			p:          auth.AllOf(auth.HasRole(auth.RoleUser), auth.HasScope("links:write")),
+			in:         auth.Input{Claims: claims([]string{auth.RoleUser}, nil)},
+			wantDenied: "AllOf(HasRole(USER), HasScope(links:write))",
+		},
+		{
+			name: "nested",
+			p:    auth.AnyOf(auth.HasRole(auth.RoleAdmin), auth.AllOf(auth.IsSubject(), auth.HasScope("links:write"))),
+			in:   auth.Input{Claims: claims([]string{auth.RoleUser}, []string{"links:write"}), UserID: subject},
+		},
+	}
+
+	a, err := auth.New(auth.Config{})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			a.RegisterRule("test", tst.p)
+
+			err := a.Authorize(context.Background(), "test", tst.in)
+
+			switch {
+			case tst.wantErr != nil:
+				if !errors.Is(err, tst.wantErr) || auth.IsDenial(err) {
+					t.Fatalf("Should get the lookup error and not a denial: got %v", err)
+				}
+
+			case tst.wantDenied != "":
+				var d *auth.Denial
+				if !errors.As(err, &d) {
+					t.Fatalf("Should be denied: got %v", err)
+				}
+				if d.Predicate != tst.wantDenied {
+					t.Fatalf("Should name the predicate that denied: got %q, want %q", d.Predicate, tst.wantDenied)
+				}
+				if !errors.Is(err, auth.ErrForbidden) {
+					t.Fatalf("Should wrap ErrForbidden: got %v", err)
+				}
+
+			default:
+				if err != nil {
+					t.Fatalf("Should pass: got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func Test_AuthorizeResolvesOwnerOnce(t *testing.T) {
+	subject := uuid.New()
+
+	a, err := auth.New(auth.Config{})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+	a.RegisterRule("owner_twice", auth.AllOf(auth.OwnsResource(), auth.AnyOf(auth.HasRole(auth.RoleAdmin), auth.OwnsResource())))
+
+	var calls int
+	in := auth.Input{
+		Claims: auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject.String()}},
+		ResourceOwner: func(ctx context.Context) (uuid.UUID, error) {
+			calls++
+			return subject, nil
+		},
+	}
+
+	if err := a.Authorize(context.Background(), "owner_twice", in); err != nil {
+		t.Fatalf("Should pass: got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Should resolve the owner once per request: got %d calls", calls)
+	}
+}
+
+func Test_AuthorizeUnknownRule(t *testing.T) {
+	a, err := auth.New(auth.Config{})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	if a.HasRule("no_such_rule") {
+		t.Fatal("Should not have a rule that wasn't registered")
+	}
+
+	err = a.Authorize(context.Background(), "no_such_rule", auth.Input{})
+	if !auth.IsAuthError(err) {
+		t.Fatalf("Should get an auth error for an unknown rule: got %v", err)
+	}
+	if !strings.Contains(err.Error(), "no_such_rule") {
+		t.Fatalf("Should name the unknown rule: got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+)
+
+// isRevoked checks the jti of the token is not on the revocation list. If no RevocationLookup was provided, or the
+// token has no jti, this check is skipped.
+// The list lives in memory and is loaded again from the RevocationLookup once it is older than the refresh interval,
+// so a token revoked on another instance is rejected here within that interval. Revocations made through this Auth
+// are applied right away. Until the list was loaded once every token is rejected, we can't tell which ones are revoked.
+func (a *Auth) isRevoked(ctx context.Context, claims Claims) error {
+	if a.revocationLookup == nil || claims.ID == "" {
+		return nil
+	}
+
+	if err := a.revoked.refreshIfStale(a); err != nil {
+		return NewAuthError("revoked tokens not loaded: %s", err)
+	}
+
+	if a.revoked.contains(claims.ID) {
+		return NewAuthError("token %s is revoked", claims.ID)
+	}
+
+	return nil
+}
+
+// Revoke puts the access tokens on the revocation list of this Auth right away, they have to be stored with the
+// RevocationLookup as well for the other instances to learn about them.
+func (a *Auth) Revoke(revs ...session.Revocation) {
+	a.revoked.add(revs)
+}
+
+// =============================================================================
+
+// Loading the list is not tied to the request that triggered it, a request that is cancelled doesn't fail the load
+// for everybody else.
+const (
+	revocationLoadTimeout = 5 * time.Second
+	// revocationRetry is the shortest time between a failed load and the next one, a database that is down doesn't
+	// get a query on every request.
+	revocationRetry = time.Second
+)
+
+// revocationList keeps the jtis of the revoked access tokens with the time the token expires, after that the token
+// is rejected anyway and the entry can go.
+type revocationList struct {
+	refresh time.Duration
+
+	mu   sync.RWMutex
+	jtis map[string]time.Time
+	// lastLoad is when the list was last loaded, the zero value until it was loaded once. A failed load doesn't move
+	// it, lastAttempt is what keeps us from trying again on every request.
+	lastLoad    time.Time
+	lastAttempt time.Time
+	lastErr     error
+
+	// loadMu makes sure only one request loads the list at a time.
+	loadMu sync.Mutex
+}
+
+func newRevocationList(refresh time.Duration) *revocationList {
+	return &revocationList{
+		refresh: refresh,
+		jtis:    make(map[string]time.Time),
+	}
+}
+
+func (rl *revocationList) contains(jti string) bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	expires, exists := rl.jtis[jti]
+	return exists && time.Now().Before(expires)
+}
+
+func (rl *revocationList) add(revs []session.Revocation) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for _, rev := range revs {
+		rl.jtis[rev.JTI.String()] = rev.DateExpires
+	}
+}
+
+// refreshIfStale loads the list again when it is older than the refresh interval.
+// Until the list was loaded once the callers wait for the load and get its error, that is what keeps a revoked token
+// from getting in right after a restart. After that a stale list is loaded by one request while the others carry on
+// with what we have, and a failed load is logged and tried again after a short while.
+func (rl *revocationList) refreshIfStale(a *Auth) error {
+	rl.mu.RLock()
+	loaded := !rl.lastLoad.IsZero()
+	stale := time.Since(rl.lastLoad) > rl.refresh
+	lastErr := rl.lastErr
+	retry := lastErr == nil || time.Since(rl.lastAttempt) >= revocationRetry
+	rl.mu.RUnlock()
+
+	switch {
+	case loaded && (!stale || !retry):
+		return nil
+
+	case loaded:
+		if !rl.loadMu.TryLock() {
+			return nil
+		}
+		defer rl.loadMu.Unlock()
+
+		if err := rl.load(a); err != nil && a.log != nil {
+			a.log.Errorw("auth", "status", "loading revoked tokens", "ERROR", err)
+		}
+		return nil
+
+	case !retry:
+		return lastErr
+	}
+
+	rl.loadMu.Lock()
+	defer rl.loadMu.Unlock()
+
+	// Somebody else could have loaded it, or failed to, while we waited.
+	rl.mu.RLock()
+	loaded = !rl.lastLoad.IsZero()
+	lastErr = rl.lastErr
+	retry = lastErr == nil || time.Since(rl.lastAttempt) >= revocationRetry
+	rl.mu.RUnlock()
+
+	switch {
+	case loaded:
+		return nil
+	case !retry:
+		return lastErr
+	}
+
+	return rl.load(a)
+}
+
+// load queries the list and merges it with what we have. The caller holds loadMu.
+func (rl *revocationList) load(a *Auth) error {
+	ctx, cancel := context.WithTimeout(context.Background(), revocationLoadTimeout)
+	defer cancel()
+
+	revs, err := a.revocationLookup.QueryRevocations(ctx)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.lastAttempt = now
+
+	if err != nil {
+		rl.lastErr = err
+		return err
+	}
+
+	// Revocations are never taken back, so what we loaded is merged with what we have. A token revoked here while
+	// the query was running is not lost, and the entries that expired are dropped.
+	jtis := make(map[string]time.Time, len(revs))
+	for jti, expires := range rl.jtis {
+		if now.Before(expires) {
+			jtis[jti] = expires
+		}
+	}
+	for _, rev := range revs {
+		jtis[rev.JTI.String()] = rev.DateExpires
+	}
+
+	rl.jtis = jtis
+	rl.lastLoad = now
+	rl.lastErr = nil
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/core/session"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/google/uuid"
+)
+
+// revocationLookup is a RevocationLookup the test can add revocations to and make fail.
+type revocationLookup struct {
+	mu    sync.Mutex
+	revs  []session.Revocation
+	err   error
+	calls int
+}
+
+func (rl *revocationLookup) QueryRevocations(ctx context.Context) ([]session.Revocation, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.calls++
+	if rl.err != nil {
+		return nil, rl.err
+	}
+	return append([]session.Revocation(nil), rl.revs...), nil
+}
+
+func (rl *revocationLookup) set(revs []session.Revocation, err error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.revs = revs
+	rl.err = err
+}
+
+func Test_Revocation(t *testing.T) {
+	kl := keyLookup{private: make(map[string]string)}
+	kl.set(t, "kid-1")
+
+	rl := revocationLookup{err: errors.New("database down")}
+
+	const refresh = 50 * time.Millisecond
+
+	a, err := auth.New(auth.Config{
+		KeyLookup:         &kl,
+		Issuer:            "test",
+		RevocationLookup:  &rl,
+		RevocationRefresh: refresh,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	type token struct {
+		bearer string
+		rev    session.Revocation
+	}
+	newToken := func() token {
+		claims := newClaims()
+		jti := uuid.New()
+		claims.ID = jti.String()
+
+		str, err := a.GenerateToken("kid-1", claims)
+		if err != nil {
+			t.Fatalf("Should be able to sign: %s", err)
+		}
+		return token{
+			bearer: "Bearer " + str,
+			rev:    session.Revocation{JTI: jti, DateExpires: claims.ExpiresAt.Time},
+		}
+	}
+	authenticate := func(tkn token) error {
+		_, err := a.Authenticate(context.Background(), tkn.bearer)
+		return err
+	}
+
+	t1 := newToken()
+
+	// -------------------------------------------------------------------------
+	// Until the list was loaded once nothing gets in, we can't tell which tokens are revoked.
+
+	if err := authenticate(t1); !auth.IsAuthError(err) {
+		t.Fatalf("Should reject tokens before the list is loaded: got %v", err)
+	}
+
+	rl.set(nil, nil)
+
+	// A failed load is tried again after a short while, not on every request.
+	deadline := time.Now().Add(3 * time.Second)
+	for authenticate(t1) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Should accept tokens once the list is loaded")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// -------------------------------------------------------------------------
+	// A token revoked here is rejected right away, without waiting for a refresh.
+
+	t2 := newToken()
+	a.Revoke(t2.rev)
+
+	if err := authenticate(t2); !auth.IsAuthError(err) {
+		t.Fatalf("Should reject a token revoked here right away: got %v", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A token revoked by another instance is rejected right after the next refresh, and the one revoked here isn't
+	// lost even though the loaded list doesn't have it.
+
+	t3 := newToken()
+	rl.set([]session.Revocation{t3.rev}, nil)
+
+	time.Sleep(refresh + 10*time.Millisecond)
+
+	if err := authenticate(t3); !auth.IsAuthError(err) {
+		t.Fatalf("Should reject a token revoked elsewhere after the refresh: got %v", err)
+	}
+	if err := authenticate(t2); !auth.IsAuthError(err) {
+		t.Fatalf("Should still reject a token revoked here after the refresh: got %v", err)
+	}
+	if err := authenticate(t1); err != nil {
+		t.Fatalf("Should accept a token that wasn't revoked: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A failed refresh keeps the list, and the next request tries again instead of trusting the stale list for a
+	// whole interval.
+
+	t4 := newToken()
+	rl.set([]session.Revocation{t3.rev}, errors.New("database down"))
+
+	time.Sleep(refresh + 10*time.Millisecond)
+
+	if err := authenticate(t3); !auth.IsAuthError(err) {
+		t.Fatalf("Should keep rejecting revoked tokens when a refresh fails: got %v", err)
+	}
+
+	rl.set([]session.Revocation{t3.rev, t4.rev}, nil)
+
+	deadline = time.Now().Add(3 * time.Second)
+	for authenticate(t4) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Should load the list again soon after a failed refresh")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -7,4 +7,22 @@ const (
 	RuleAdminOnly      = "rule_admin_only"
 	RuleUserOnly       = "rule_user_only"
 	RuleAdminOrSubject = "rule_admin_or_subject"
+	RuleAdminOrOwner   = "rule_admin_or_owner"
 )
+
+// These are the roles a user can have.
+const (
+	RoleAdmin = "ADMIN"
+	RoleUser  = "USER"
+)
+
+// defaultRules returns the rules every Auth starts with, more can be added with RegisterRule.
+func defaultRules() map[string]Predicate {
+	return map[string]Predicate{
+		RuleAny:            AnyOf(HasRole(RoleAdmin), HasRole(RoleUser)),
+		RuleAdminOnly:      HasRole(RoleAdmin),
+		RuleUserOnly:       HasRole(RoleUser),
+		RuleAdminOrSubject: AnyOf(HasRole(RoleAdmin), IsSubject()),
+		RuleAdminOrOwner:   AnyOf(HasRole(RoleAdmin), OwnsResource()),
+	}
+}
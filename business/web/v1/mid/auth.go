@@ -3,6 +3,7 @@ package mid
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
@@ -38,9 +39,24 @@ func Authenticate(a *auth.Auth) web.Middleware {
 	return m
 }
 
-// Authorize validates that an authenticated user has at least one role from a
-// specified list. This method constructs the actual function that is used.
+// ResourceResolver finds the owner of the resource the request is acting on, e.g. by reading the id in the path and
+// looking it up in the store. An error it returns is sent back as is, so it should be a trusted error like a 404.
+type ResourceResolver func(ctx context.Context, r *http.Request) (uuid.UUID, error)
+
+// Authorize validates that an authenticated user passes the named rule.
+// This method constructs the actual function that is used. A rule name that isn't registered is a programming error,
+// so it panics while the routes are bound instead of failing every request.
 func Authorize(a *auth.Auth, rule string) web.Middleware {
+	return AuthorizeResource(a, rule, nil)
+}
+
+// AuthorizeResource is Authorize for routes acting on a resource that belongs to a user, the resolver is only called
+// when the rule needs to know the owner, so an admin never pays for the lookup.
+func AuthorizeResource(a *auth.Auth, rule string, resolve ResourceResolver) web.Middleware {
+	if !a.HasRule(rule) {
+		panic(fmt.Sprintf("authorization rule %q is not registered", rule))
+	}
+
 	m := func(handler web.Handler) web.Handler {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			// We get the claims out of the context
@@ -61,9 +77,24 @@ func Authorize(a *auth.Auth, rule string) web.Middleware {
 				}
 				ctx = auth.SetUserID(ctx, userID)
 			}
-			// Then we call authorize
-			if err := a.Authorize(claims, userID, rule); err != nil {
-				return auth.NewAuthError("authorize: you are not authorized for that action, claims[%v] rule[%v]: %s", claims.Roles, rule, err)
+
+			in := auth.Input{
+				Claims: claims,
+				UserID: userID,
+			}
+			// The owner is resolved lazily, auth makes sure it happens at most once however many predicates ask.
+			if resolve != nil {
+				in.ResourceOwner = func(ctx context.Context) (uuid.UUID, error) {
+					return resolve(ctx, r)
+				}
+			}
+
+			// Then we call authorize, a denial says which predicate failed so it goes into the auth error we log.
+			if err := a.Authorize(ctx, rule, in); err != nil {
+				if auth.IsDenial(err) {
+					return auth.NewAuthError("authorize: you are not authorized for that action, claims[%v]: %s", claims.Roles, err)
+				}
+				return err
 			}
 
 			return handler(ctx, w, r)
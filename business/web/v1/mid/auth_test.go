@@ -0,0 +1,107 @@
+package mid_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/mid"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+func Test_AuthorizeResource(t *testing.T) {
+	const ruleOwnerOnly = "rule_owner_only"
+
+	a, err := auth.New(auth.Config{Issuer: "test"})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+	a.RegisterRule(ruleOwnerOnly, auth.OwnsResource())
+
+	owner := uuid.New()
+	errLookup := errors.New("lookup failed")
+
+	tt := []struct {
+		name    string
+		rule    string
+		subject uuid.UUID
+		roles   []string
+		// lookupErr is what the resolver fails with, nil when it finds the owner.
+		lookupErr   error
+		wantCalled  bool
+		wantResolve int
+		wantAuthErr bool
+		wantErr     error
+	}{
+		{name: "owner", rule: ruleOwnerOnly, subject: owner, wantCalled: true, wantResolve: 1},
+		{name: "someone else", rule: ruleOwnerOnly, subject: uuid.New(), wantResolve: 1, wantAuthErr: true},
+		{name: "owner lookup fails", rule: ruleOwnerOnly, subject: owner, lookupErr: errLookup, wantResolve: 1, wantErr: errLookup},
+		{name: "admin skips the lookup", rule: auth.RuleAdminOrOwner, subject: uuid.New(), roles: []string{auth.RoleAdmin}, wantCalled: true},
+		{name: "admin or owner, owner", rule: auth.RuleAdminOrOwner, subject: owner, roles: []string{auth.RoleUser}, wantCalled: true, wantResolve: 1},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			var resolved int
+			resolve := func(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+				resolved++
+				return owner, tst.lookupErr
+			}
+
+			var called bool
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				called = true
+				return nil
+			}
+
+			h := mid.AuthorizeResource(a, tst.rule, resolve)(handler)
+
+			claims := auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: tst.subject.String()},
+				Roles:            tst.roles,
+			}
+			ctx := auth.SetClaims(context.Background(), claims)
+
+			err := h(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if called != tst.wantCalled {
+				t.Errorf("Should call the handler %t, got %t", tst.wantCalled, called)
+			}
+			if resolved != tst.wantResolve {
+				t.Errorf("Should resolve the owner %d times, got %d", tst.wantResolve, resolved)
+			}
+
+			switch {
+			case tst.wantAuthErr:
+				if !auth.IsAuthError(err) {
+					t.Errorf("Should fail with an auth error, got %v", err)
+				}
+			case tst.wantErr != nil:
+				if !errors.Is(err, tst.wantErr) {
+					t.Errorf("Should fail with %v, got %v", tst.wantErr, err)
+				}
+			case err != nil:
+				t.Errorf("Should pass, got %s", err)
+			}
+		})
+	}
+}
+
+func Test_AuthorizeUnknownRule(t *testing.T) {
+	a, err := auth.New(auth.Config{Issuer: "test"})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Should panic when binding a route to a rule that isn't registered")
+		}
+	}()
+
+	mid.Authorize(a, "rule_nobody_registered")
+}
@@ -0,0 +1,56 @@
+// Package paging provides support for query paging.
+// Just like order, this is a web concern that lives in the business layer because we are only using it in the app
+// layer against a request looking for the query string.
+package paging
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+)
+
+// Set of defaults for paging, used when the caller doesn't provide a value.
+const (
+	DefaultPage        = 1
+	DefaultRowsPerPage = 10
+	MaxRowsPerPage     = 100
+)
+
+// Page represents the requested page and rows per page.
+type Page struct {
+	Number      int
+	RowsPerPage int
+}
+
+// ParseRequest parses the request for the page and rows query string.
+// e.g. on query "page=2&rows=25"
+// Anything that is not a positive number is rejected as a field error, and rows is capped so a caller can't ask us
+// to pull the whole table in one call.
+func ParseRequest(r *http.Request) (Page, error) {
+	values := r.URL.Query()
+
+	number := DefaultPage
+	if page := values.Get("page"); page != "" {
+		var err error
+		number, err = strconv.Atoi(page)
+		if err != nil || number <= 0 {
+			return Page{}, validate.NewFieldsError("page", fmt.Errorf("page must be a positive number: %q", page))
+		}
+	}
+
+	rowsPerPage := DefaultRowsPerPage
+	if rows := values.Get("rows"); rows != "" {
+		var err error
+		rowsPerPage, err = strconv.Atoi(rows)
+		if err != nil || rowsPerPage <= 0 || rowsPerPage > MaxRowsPerPage {
+			return Page{}, validate.NewFieldsError("rows", fmt.Errorf("rows must be between 1 and %d: %q", MaxRowsPerPage, rows))
+		}
+	}
+
+	return Page{
+		Number:      number,
+		RowsPerPage: rowsPerPage,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package paging_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/paging"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/validate"
+)
+
+func Test_ParseRequest(t *testing.T) {
+	tt := []struct {
+		name    string
+		query   string
+		want    paging.Page
+		wantErr bool
+	}{
+		{name: "defaults", query: "", want: paging.Page{Number: paging.DefaultPage, RowsPerPage: paging.DefaultRowsPerPage}},
+		{name: "page and rows", query: "page=3&rows=25", want: paging.Page{Number: 3, RowsPerPage: 25}},
+		{name: "max rows", query: "rows=100", want: paging.Page{Number: paging.DefaultPage, RowsPerPage: paging.MaxRowsPerPage}},
+		{name: "over max rows", query: "rows=101", wantErr: true},
+		{name: "zero rows", query: "rows=0", wantErr: true},
+		{name: "rows not a number", query: "rows=ten", wantErr: true},
+		{name: "zero page", query: "page=0", wantErr: true},
+		{name: "negative page", query: "page=-1", wantErr: true},
+		{name: "page not a number", query: "page=one", wantErr: true},
+		{name: "unknown params are ignored", query: "size=5", want: paging.Page{Number: paging.DefaultPage, RowsPerPage: paging.DefaultRowsPerPage}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/users?"+tst.query, nil)
+
+			got, err := paging.ParseRequest(r)
+			if tst.wantErr {
+				if !validate.IsFieldErrors(err) {
+					t.Fatalf("Should get a field error: got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Should be able to parse the page: %s", err)
+			}
+			if got != tst.want {
+				t.Fatalf("Should get the right page: got %+v, want %+v", got, tst.want)
+			}
+		})
+	}
+}
@@ -45,3 +45,25 @@ func GetError(err error) *Error {
 	}
 	return re
 }
+
+// =============================================================================
+
+// PageDocument is the form used for API responses from query API's that return a page of items.
+// Every listing endpoint responds with the same envelope, so the caller always knows where to find the items and how
+// to ask for the next page. We are using generics here so the items keep their concrete app layer type.
+type PageDocument[T any] struct {
+	Items       []T `json:"items"`
+	Total       int `json:"total"`
+	Page        int `json:"page"`
+	RowsPerPage int `json:"rowsPerPage"`
+}
+
+// NewPageDocument constructs a response value for a web paging result.
+func NewPageDocument[T any](items []T, total int, page int, rowsPerPage int) PageDocument[T] {
+	return PageDocument[T]{
+		Items:       items,
+		Total:       total,
+		Page:        page,
+		RowsPerPage: rowsPerPage,
+	}
+}
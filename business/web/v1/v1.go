@@ -2,9 +2,12 @@ package v1
 
 import (
 	"os"
+	"time"
 
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
 	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/mid"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
 	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -21,6 +24,15 @@ type APIMuxConfig struct {
 	Log  *zap.SugaredLogger
 	Auth *auth.Auth
 	DB   *gorm.DB
+	// The keys tokens are signed with, their public halves are published as a JWKS.
+	Keys *keystore.KeyStore
+	// How long the tokens we issue are valid for.
+	TokenExpiry time.Duration
+	// How long the refresh tokens we issue can be exchanged for a new pair.
+	RefreshTokenExpiry time.Duration
+	// Every group describes the routes it binds in here, so the API documentation is generated from what is really
+	// registered.
+	Doc *openapi.Document
 }
 
 // RouteAdder defines behavior that sets the routes to bind for an instance
@@ -46,10 +58,13 @@ type RouteAdder interface {
 // This will later let us rip out the gin mux and going to use the standard library mux because we are gonna create that
 // abstraction.
 // Notice we are not creating an abstraction to an interface we are creating an abstraction to the concrete type.
-func APIMux(cfg APIMuxConfig, routeAdder RouteAdder) *web.App {
+// The RouteAdder is variadic, so a binary can mount as many route sets as it needs and nothing more.
+func APIMux(cfg APIMuxConfig, routeAdders ...RouteAdder) *web.App {
 	app := web.NewApp(cfg.Shutdown, mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics(), mid.Panics())
 
-	routeAdder.Add(app, cfg)
+	for _, routeAdder := range routeAdders {
+		routeAdder.Add(app, cfg)
+	}
 
 	return app
 }
@@ -0,0 +1,45 @@
+// Package v2 is the mux configuration for the redesigned API.
+// It lives next to v1 so both versions can be mounted side by side during a migration, each version builds its own
+// App with its own routes, so a v2 change can never leak into a v1 route and the other way around.
+// The middleware is shared for now, if v2 ever needs a different stack it changes in here only.
+package v2
+
+import (
+	"os"
+
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/auth"
+	"github.com/MinaMamdouh2/URL-Shortener/business/web/v1/mid"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/openapi"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// APIMuxConfig contains all the mandatory systems required by handlers.
+type APIMuxConfig struct {
+	Build    string
+	Shutdown chan os.Signal
+	Log      *zap.SugaredLogger
+	Auth     *auth.Auth
+	DB       *gorm.DB
+	// Every group describes the routes it binds in here, it is served at "/v2/openapi.json".
+	Doc *openapi.Document
+}
+
+// RouteAdder defines behavior that sets the routes to bind for an instance
+// of the service.
+type RouteAdder interface {
+	Add(app *web.App, cfg APIMuxConfig)
+}
+
+// APIMux constructs a http.Handler with all application routes bound.
+// Just like v1, every RouteAdder passed in gets to bind its routes, so a binary decides which route sets it mounts.
+func APIMux(cfg APIMuxConfig, routeAdders ...RouteAdder) *web.App {
+	app := web.NewApp(cfg.Shutdown, mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics(), mid.Panics())
+
+	for _, routeAdder := range routeAdders {
+		routeAdder.Add(app, cfg)
+	}
+
+	return app
+}
@@ -0,0 +1,209 @@
+// Package jwks provides support for the JSON Web Key Set format (RFC 7517), so the public keys we sign tokens with can
+// be published and other services can verify our tokens without us sharing PEM files with them.
+package jwks
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Key represents a single public key in the JWK format. Only the fields for the key types we sign with are here.
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA public key parameters.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP (Ed25519) public key parameters, OKP only uses X.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Set represents a JSON Web Key Set, this is the document that is served at "/.well-known/jwks.json".
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// Key searches the set for the specified kid.
+func (s Set) Key(kid string) (Key, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+
+	return Key{}, false
+}
+
+// NewKey builds the JWK for a public key used for signing tokens, the algorithm comes from the type of the key.
+// RSA keys sign with RS256, EC keys with ES256 or ES384 depending on the curve and Ed25519 keys with EdDSA.
+func NewKey(kid string, publicKey crypto.PublicKey) (Key, error) {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return Key{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   encode(pub.N.Bytes()),
+			E:   encode(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+
+	case *ecdsa.PublicKey:
+		var alg, crv string
+		switch pub.Curve {
+		case elliptic.P256():
+			alg, crv = "ES256", "P-256"
+		case elliptic.P384():
+			alg, crv = "ES384", "P-384"
+		default:
+			return Key{}, fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+		}
+
+		// The coordinates are always the full size of the curve, even when they start with zeros.
+		size := (pub.Curve.Params().BitSize + 7) / 8
+
+		return Key{
+			Kty: "EC",
+			Kid: kid,
+			Use: "sig",
+			Alg: alg,
+			Crv: crv,
+			X:   encode(pub.X.FillBytes(make([]byte, size))),
+			Y:   encode(pub.Y.FillBytes(make([]byte, size))),
+		}, nil
+
+	case ed25519.PublicKey:
+		return Key{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   encode(pub),
+		}, nil
+	}
+
+	return Key{}, fmt.Errorf("unsupported public key type %T", publicKey)
+}
+
+// PublicKey converts the JWK back into a public key.
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding n: %w", err)
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding e: %w", err)
+		}
+		if len(n) == 0 || len(e) == 0 {
+			return nil, errors.New("missing rsa parameters")
+		}
+
+		pub := rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return &pub, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x: %w", err)
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding y: %w", err)
+		}
+
+		pub := ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		// A point that isn't on the curve is not a key, converting it for ECDH is what checks that.
+		if _, err := pub.ECDH(); err != nil {
+			return nil, fmt.Errorf("invalid ec key: %w", err)
+		}
+		return &pub, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// PEM converts the JWK into a PKIX PEM encoded public key, the format the auth package works with.
+func (k Key) PEM() (string, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return "", err
+	}
+
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("marshaling public key: %w", err)
+	}
+
+	block := pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: asn1Bytes,
+	}
+
+	var b bytes.Buffer
+	if err := pem.Encode(&b, &block); err != nil {
+		return "", fmt.Errorf("encoding public key: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// =============================================================================
+
+// The numbers in a JWK are big-endian bytes in base64url without padding.
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,92 @@
+package jwks_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/jwks"
+)
+
+func Test_KeyRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate an rsa key: %s", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a P-256 key: %s", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a P-384 key: %s", err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate an ed25519 key: %s", err)
+	}
+
+	tt := []struct {
+		name    string
+		pub     crypto.PublicKey
+		wantKty string
+		wantAlg string
+	}{
+		{name: "rsa", pub: &rsaKey.PublicKey, wantKty: "RSA", wantAlg: "RS256"},
+		{name: "p256", pub: &p256Key.PublicKey, wantKty: "EC", wantAlg: "ES256"},
+		{name: "p384", pub: &p384Key.PublicKey, wantKty: "EC", wantAlg: "ES384"},
+		{name: "ed25519", pub: edPub, wantKty: "OKP", wantAlg: "EdDSA"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			key, err := jwks.NewKey("kid-1", tst.pub)
+			if err != nil {
+				t.Fatalf("Should be able to build the jwk: %s", err)
+			}
+			if key.Kid != "kid-1" || key.Kty != tst.wantKty || key.Alg != tst.wantAlg || key.Use != "sig" {
+				t.Fatalf("Should describe the key: got %+v", key)
+			}
+
+			got, err := key.PEM()
+			if err != nil {
+				t.Fatalf("Should be able to convert the jwk to pem: %s", err)
+			}
+
+			der, err := x509.MarshalPKIXPublicKey(tst.pub)
+			if err != nil {
+				t.Fatalf("Should be able to marshal the original key: %s", err)
+			}
+			want := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+			if got != want {
+				t.Fatalf("Should get back the same key:\ngot  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func Test_KeyRejectsPointOffCurve(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a P-256 key: %s", err)
+	}
+
+	key, err := jwks.NewKey("kid-1", &priv.PublicKey)
+	if err != nil {
+		t.Fatalf("Should be able to build the jwk: %s", err)
+	}
+
+	// Swapping the coordinates gives a point that is not on the curve.
+	key.X, key.Y = key.Y, key.X
+
+	if _, err := key.PublicKey(); err == nil {
+		t.Fatal("Should reject a point that is not on the curve")
+	}
+}
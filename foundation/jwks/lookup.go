@@ -0,0 +1,199 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned when the kid is not in the remote set, even after fetching it again.
+var ErrKeyNotFound = errors.New("kid not found in jwks")
+
+// fetchTimeout bounds a single fetch of the set.
+const fetchTimeout = 10 * time.Second
+
+// LookupConfig represents the information required to construct a Lookup.
+type LookupConfig struct {
+	// URL of the remote set, e.g. "https://auth.example.com/.well-known/jwks.json".
+	URL    string
+	Client *http.Client
+	// How often the set is fetched in the background, defaults to 5 minutes.
+	RefreshInterval time.Duration
+	// The shortest time between two fetches caused by an unknown kid, defaults to 30 seconds. Without it anybody
+	// could make us hammer the remote service by sending tokens with made up kids.
+	MinRefetchInterval time.Duration
+	// OnError is called when a background refresh fails, the keys we already have are kept. Optional.
+	OnError func(err error)
+}
+
+// Lookup implements the auth.KeyLookup interface on top of a remote JWKS. It only knows public keys, so a service
+// using it can verify tokens but never sign them.
+type Lookup struct {
+	cfg LookupConfig
+
+	// fetchMu makes sure only one fetch is running at a time.
+	fetchMu sync.Mutex
+
+	mu   sync.RWMutex
+	keys map[string]string
+	// lastFetch is when we last tried to fetch, a failed attempt counts too so a remote that is down doesn't get
+	// called on every request.
+	lastFetch time.Time
+
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewLookup constructs a Lookup and fetches the set for the first time, so a misconfigured URL fails at startup. The
+// set is refreshed in the background until Close is called.
+func NewLookup(ctx context.Context, cfg LookupConfig) (*Lookup, error) {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: fetchTimeout}
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 5 * time.Minute
+	}
+	if cfg.MinRefetchInterval <= 0 {
+		cfg.MinRefetchInterval = 30 * time.Second
+	}
+
+	l := Lookup{
+		cfg:      cfg,
+		keys:     make(map[string]string),
+		shutdown: make(chan struct{}),
+	}
+
+	if err := l.fetch(ctx); err != nil {
+		return nil, fmt.Errorf("initial fetch: %w", err)
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.refresh()
+	}()
+
+	return &l, nil
+}
+
+// Close stops the background refresh.
+func (l *Lookup) Close() {
+	close(l.shutdown)
+	l.wg.Wait()
+}
+
+// PrivateKey is part of the auth.KeyLookup interface, a JWKS never carries private keys.
+func (l *Lookup) PrivateKey(kid string) (string, error) {
+	return "", errors.New("private keys are not available from a jwks")
+}
+
+// PublicKey returns the PEM encoded public key for the kid. A kid we don't know causes the set to be fetched again,
+// that is how a key the remote service just started signing with is picked up before the next refresh.
+func (l *Lookup) PublicKey(kid string) (string, error) {
+	if pem, exists := l.key(kid); exists {
+		return pem, nil
+	}
+
+	l.mu.RLock()
+	recent := time.Since(l.lastFetch) < l.cfg.MinRefetchInterval
+	l.mu.RUnlock()
+
+	if !recent {
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		defer cancel()
+
+		if err := l.fetch(ctx); err != nil {
+			return "", fmt.Errorf("refetching jwks: %w", err)
+		}
+	}
+
+	if pem, exists := l.key(kid); exists {
+		return pem, nil
+	}
+
+	return "", ErrKeyNotFound
+}
+
+// =============================================================================
+
+func (l *Lookup) key(kid string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	pem, exists := l.keys[kid]
+	return pem, exists
+}
+
+func (l *Lookup) refresh() {
+	ticker := time.NewTicker(l.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+			err := l.fetch(ctx)
+			cancel()
+
+			if err != nil && l.cfg.OnError != nil {
+				l.cfg.OnError(err)
+			}
+
+		case <-l.shutdown:
+			return
+		}
+	}
+}
+
+// fetch downloads the set and replaces the keys we have, keys that were removed from the set stop being accepted.
+func (l *Lookup) fetch(ctx context.Context) error {
+	l.fetchMu.Lock()
+	defer l.fetchMu.Unlock()
+
+	l.mu.Lock()
+	l.lastFetch = time.Now()
+	l.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.cfg.URL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := l.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", l.cfg.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: status %d", l.cfg.URL, resp.StatusCode)
+	}
+
+	// Same as for PEM files, a set is never anywhere near a megabyte.
+	var set Set
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&set); err != nil {
+		return fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	keys := make(map[string]string, len(set.Keys))
+	for _, k := range set.Keys {
+		// A key type we don't support can't be used by us, but it shouldn't stop us from using the others.
+		pem, err := k.PEM()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pem
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.keys = keys
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/jwks"
+)
+
+// remote serves a set that the test can change, and counts how often it was fetched.
+type remote struct {
+	mu      sync.Mutex
+	set     jwks.Set
+	fetches atomic.Int32
+}
+
+func (rm *remote) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rm.fetches.Add(1)
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	json.NewEncoder(w).Encode(rm.set)
+}
+
+func (rm *remote) add(t *testing.T, kid string) jwks.Key {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+	key, err := jwks.NewKey(kid, pub)
+	if err != nil {
+		t.Fatalf("Should be able to build the jwk: %s", err)
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.set.Keys = append(rm.set.Keys, key)
+
+	return key
+}
+
+func Test_Lookup(t *testing.T) {
+	rm := remote{}
+	first := rm.add(t, "kid-1")
+
+	// A key type we don't support is skipped, the others are still used.
+	rm.set.Keys = append(rm.set.Keys, jwks.Key{Kty: "oct", Kid: "kid-sym"})
+
+	srv := httptest.NewServer(&rm)
+	defer srv.Close()
+
+	l, err := jwks.NewLookup(context.Background(), jwks.LookupConfig{
+		URL:                srv.URL,
+		RefreshInterval:    time.Hour,
+		MinRefetchInterval: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct the lookup: %s", err)
+	}
+	defer l.Close()
+
+	got, err := l.PublicKey("kid-1")
+	if err != nil {
+		t.Fatalf("Should find the kid from the first fetch: %s", err)
+	}
+	if want, _ := first.PEM(); got != want {
+		t.Fatalf("Should get the pem of the key:\ngot  %s\nwant %s", got, want)
+	}
+
+	if _, err := l.PrivateKey("kid-1"); err == nil {
+		t.Fatal("Should never return a private key")
+	}
+
+	// A kid that shows up in the remote set right after a fetch is not fetched for until the min interval passed.
+	second := rm.add(t, "kid-2")
+	fetches := rm.fetches.Load()
+
+	if _, err := l.PublicKey("kid-2"); !errors.Is(err, jwks.ErrKeyNotFound) {
+		t.Fatalf("Should not refetch within the min interval: got %v", err)
+	}
+	if rm.fetches.Load() != fetches {
+		t.Fatal("Should not have called the remote within the min interval")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	got, err = l.PublicKey("kid-2")
+	if err != nil {
+		t.Fatalf("Should refetch on a kid miss: %s", err)
+	}
+	if want, _ := second.PEM(); got != want {
+		t.Fatalf("Should get the pem of the new key:\ngot  %s\nwant %s", got, want)
+	}
+
+	if _, err := l.PublicKey("kid-sym"); !errors.Is(err, jwks.ErrKeyNotFound) {
+		t.Fatalf("Should skip the key type we don't support: got %v", err)
+	}
+}
+
+func Test_LookupRefresh(t *testing.T) {
+	rm := remote{}
+	rm.add(t, "kid-1")
+
+	srv := httptest.NewServer(&rm)
+	defer srv.Close()
+
+	l, err := jwks.NewLookup(context.Background(), jwks.LookupConfig{
+		URL:                srv.URL,
+		RefreshInterval:    50 * time.Millisecond,
+		MinRefetchInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct the lookup: %s", err)
+	}
+	defer l.Close()
+
+	// The key is removed from the remote set, the background refresh drops it.
+	rm.mu.Lock()
+	rm.set.Keys = nil
+	rm.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := l.PublicKey("kid-1"); errors.Is(err, jwks.ErrKeyNotFound) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Should drop a key the remote removed on the next refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_LookupFailsAtStartup(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := jwks.NewLookup(context.Background(), jwks.LookupConfig{URL: srv.URL}); err == nil {
+		t.Fatal("Should fail when the first fetch fails")
+	}
+}
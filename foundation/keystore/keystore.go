@@ -5,43 +5,63 @@ package keystore
 
 import (
 	"bytes"
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"path"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/jwks"
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/pemkey"
+)
+
+// Set of error variables for looking up keys.
+var (
+	ErrKIDNotFound = errors.New("kid lookup failed")
+	ErrKIDRetired  = errors.New("kid is retired")
+	// ErrActiveKIDRemoved is returned by Reload when the file of the active kid is gone. The key is kept, new tokens
+	// still have to be signed with something, until the active kid is switched to another key.
+	ErrActiveKIDRemoved = errors.New("file of the active kid is gone, switch the active kid to retire it")
 )
 
 // PrivateKey represents key information.
-// Holds both the parsed *rsa.PrivateKey and the original PEM bytes.
+// Holds both the parsed key and the original PEM bytes. The key is an *rsa.PrivateKey, an *ecdsa.PrivateKey or an
+// ed25519.PrivateKey, they all are a crypto.Signer which is what gives us the public half.
 type PrivateKey struct {
-	PK  *rsa.PrivateKey
+	PK  crypto.Signer
 	PEM []byte
 }
 
 // KeyStore represents an in memory store implementation of the
 // KeyLookup interface for use with the auth package.
+// The keys can be reloaded while the service is running, so every access goes through the mutex.
 type KeyStore struct {
+	mu    sync.RWMutex
 	store map[string]PrivateKey
+	// retired holds the kids whose file is gone and when we noticed. They are still good for verifying tokens until
+	// their grace period ends, but nothing gets signed with them anymore.
+	retired   map[string]time.Time
+	activeKID string
 }
 
 // New constructs an empty KeyStore ready for use.
 func New() *KeyStore {
-	return &KeyStore{
-		store: make(map[string]PrivateKey),
-	}
+	return NewMap(make(map[string]PrivateKey))
 }
 
 // NewMap constructs a KeyStore with an initial set of keys.
 func NewMap(store map[string]PrivateKey) *KeyStore {
 	return &KeyStore{
-		store: store,
+		store:   store,
+		retired: make(map[string]time.Time),
 	}
 }
 
@@ -51,7 +71,173 @@ func NewMap(store map[string]PrivateKey) *KeyStore {
 // Example: /zarf/keys/54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem
 // fsys fs.FS is any file‐system abstraction (e.g., os.DirFS("/keys") or an embedded FS).
 func NewFS(fsys fs.FS) (*KeyStore, error) {
-	ks := New()
+	store, err := readKeys(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMap(store), nil
+}
+
+// ActiveKID returns the kid new tokens are signed with.
+func (ks *KeyStore) ActiveKID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.activeKID
+}
+
+// SetActiveKID switches the kid new tokens are signed with, tokens signed with the previous one stay valid.
+func (ks *KeyStore) SetActiveKID(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, found := ks.store[kid]; !found {
+		return fmt.Errorf("kid[%s]: %w", kid, ErrKIDNotFound)
+	}
+	if _, retired := ks.retired[kid]; retired {
+		return fmt.Errorf("kid[%s]: %w", kid, ErrKIDRetired)
+	}
+
+	ks.activeKID = kid
+
+	return nil
+}
+
+// Reload reads the keys in the directory again and swaps them in at once.
+// New and changed files are used right away. A key whose file is gone is retired, it is still accepted for verifying
+// tokens during the grace period, so the tokens already signed with it don't suddenly fail, and removed after that.
+// The active key is never retired, when its file is gone the other changes are still applied and ErrActiveKIDRemoved
+// is returned, every reload, until the active kid is switched away from it.
+// It returns the kids whose public key changed or went away, anything caching those keys has to drop them.
+func (ks *KeyStore) Reload(fsys fs.FS, grace time.Duration) ([]string, error) {
+	// The files are read before taking the lock, a broken file leaves the store as it was.
+	store, err := readKeys(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	var changed []string
+
+	for kid, key := range store {
+		if old, found := ks.store[kid]; found && !bytes.Equal(old.PEM, key.PEM) {
+			changed = append(changed, kid)
+		}
+		ks.store[kid] = key
+		delete(ks.retired, kid)
+	}
+
+	var activeRemoved bool
+
+	for kid := range ks.store {
+		if _, found := store[kid]; found {
+			continue
+		}
+
+		if kid == ks.activeKID {
+			activeRemoved = true
+			continue
+		}
+
+		retiredAt, retired := ks.retired[kid]
+		switch {
+		case !retired:
+			ks.retired[kid] = now
+		case now.Sub(retiredAt) >= grace:
+			delete(ks.store, kid)
+			delete(ks.retired, kid)
+			changed = append(changed, kid)
+		}
+	}
+
+	slices.Sort(changed)
+
+	if activeRemoved {
+		return changed, fmt.Errorf("kid[%s]: %w", ks.activeKID, ErrActiveKIDRemoved)
+	}
+
+	return changed, nil
+}
+
+// PrivateKey searches the key store for a given kid and returns the private key.
+// A retired key can't be used for signing anymore.
+func (ks *KeyStore) PrivateKey(kid string) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	privateKey, found := ks.store[kid]
+	if !found {
+		return "", ErrKIDNotFound
+	}
+	if _, retired := ks.retired[kid]; retired {
+		return "", ErrKIDRetired
+	}
+
+	return string(privateKey.PEM), nil
+}
+
+// PublicKey searches the key store for a given kid and returns the public key.
+func (ks *KeyStore) PublicKey(kid string) (string, error) {
+	ks.mu.RLock()
+	privateKey, found := ks.store[kid]
+	ks.mu.RUnlock()
+
+	if !found {
+		return "", ErrKIDNotFound
+	}
+
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(privateKey.PK.Public())
+	if err != nil {
+		return "", fmt.Errorf("marshaling public key: %w", err)
+	}
+
+	block := pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: asn1Bytes,
+	}
+
+	var b bytes.Buffer
+	if err := pem.Encode(&b, &block); err != nil {
+		return "", fmt.Errorf("encoding to private file: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// JWKS returns the public keys of the store as a JSON Web Key Set, so they can be published for other services to
+// verify our tokens with. The keys are sorted by kid so the document is stable.
+// Retired keys are still published until their grace period ends, tokens signed with them are still valid.
+func (ks *KeyStore) JWKS() (jwks.Set, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kids := slices.Sorted(maps.Keys(ks.store))
+
+	set := jwks.Set{
+		Keys: make([]jwks.Key, 0, len(kids)),
+	}
+
+	for _, kid := range kids {
+		key, err := jwks.NewKey(kid, ks.store[kid].PK.Public())
+		if err != nil {
+			return jwks.Set{}, fmt.Errorf("kid[%s]: %w", kid, err)
+		}
+		set.Keys = append(set.Keys, key)
+	}
+
+	return set, nil
+}
+
+// =============================================================================
+
+// readKeys parses every PEM file in the directory into a map keyed by kid.
+func readKeys(fsys fs.FS) (map[string]PrivateKey, error) {
+	store := make(map[string]PrivateKey)
 	// Define a walk‐callback fn that will be called for every file or directory under fsys.
 	// It receives: fileName (path relative to the root, e.g. "54bb…c1.pem")
 	// dirEntry (info on whether it’s a file or directory) & err from trying to read that directory entry.
@@ -82,17 +268,23 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return fmt.Errorf("reading auth private key: %w", err)
 		}
 
-		pk, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		pk, err := pemkey.ParsePrivate(pem)
 		if err != nil {
 			return fmt.Errorf("parsing auth private key: %w", err)
 		}
 
+		// A key we can't publish is a key nobody can verify, e.g. an EC key on a curve we don't sign with.
+		kid := strings.TrimSuffix(dirEntry.Name(), ".pem")
+		if _, err := jwks.NewKey(kid, pk.Public()); err != nil {
+			return fmt.Errorf("kid[%s]: %w", kid, err)
+		}
+
 		key := PrivateKey{
 			PK:  pk,
 			PEM: pem,
 		}
 
-		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = key
+		store[kid] = key
 
 		return nil
 	}
@@ -101,40 +293,5 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 		return nil, fmt.Errorf("walking directory: %w", err)
 	}
 
-	return ks, nil
-}
-
-// PrivateKey searches the key store for a given kid and returns the private key.
-func (ks *KeyStore) PrivateKey(kid string) (string, error) {
-	privateKey, found := ks.store[kid]
-	if !found {
-		return "", errors.New("kid lookup failed")
-	}
-
-	return string(privateKey.PEM), nil
-}
-
-// PublicKey searches the key store for a given kid and returns the public key.
-func (ks *KeyStore) PublicKey(kid string) (string, error) {
-	privateKey, found := ks.store[kid]
-	if !found {
-		return "", errors.New("kid lookup failed")
-	}
-
-	asn1Bytes, err := x509.MarshalPKIXPublicKey(&privateKey.PK.PublicKey)
-	if err != nil {
-		return "", fmt.Errorf("marshaling public key: %w", err)
-	}
-
-	block := pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: asn1Bytes,
-	}
-
-	var b bytes.Buffer
-	if err := pem.Encode(&b, &block); err != nil {
-		return "", fmt.Errorf("encoding to private file: %w", err)
-	}
-
-	return b.String(), nil
+	return store, nil
 }
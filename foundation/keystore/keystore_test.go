@@ -0,0 +1,271 @@
+package keystore_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/keystore"
+)
+
+func newKeyFile(t *testing.T) *fstest.MapFile {
+	t.Helper()
+
+	_, pk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the key: %s", err)
+	}
+
+	return &fstest.MapFile{Data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})}
+}
+
+func Test_SetActiveKID(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.pem": newKeyFile(t),
+		"b.pem": newKeyFile(t),
+	}
+
+	ks, err := keystore.NewFS(fsys)
+	if err != nil {
+		t.Fatalf("Should be able to read the keys: %s", err)
+	}
+
+	if err := ks.SetActiveKID("a"); err != nil {
+		t.Fatalf("Should be able to activate a known kid: %s", err)
+	}
+	if ks.ActiveKID() != "a" {
+		t.Fatalf("Should have the active kid: got %q", ks.ActiveKID())
+	}
+
+	if err := ks.SetActiveKID("nope"); !errors.Is(err, keystore.ErrKIDNotFound) {
+		t.Fatalf("Should refuse an unknown kid: got %v", err)
+	}
+
+	// b is retired once its file is gone.
+	delete(fsys, "b.pem")
+	if _, err := ks.Reload(fsys, time.Hour); err != nil {
+		t.Fatalf("Should be able to reload: %s", err)
+	}
+
+	if err := ks.SetActiveKID("b"); !errors.Is(err, keystore.ErrKIDRetired) {
+		t.Fatalf("Should refuse a retired kid: got %v", err)
+	}
+	if ks.ActiveKID() != "a" {
+		t.Fatalf("Should keep the active kid after a refused switch: got %q", ks.ActiveKID())
+	}
+}
+
+func Test_Reload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.pem": newKeyFile(t),
+		"b.pem": newKeyFile(t),
+	}
+
+	ks, err := keystore.NewFS(fsys)
+	if err != nil {
+		t.Fatalf("Should be able to read the keys: %s", err)
+	}
+	if err := ks.SetActiveKID("a"); err != nil {
+		t.Fatalf("Should be able to activate a: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A new file is a new key, a replaced file is reported as changed.
+
+	oldB, _ := ks.PublicKey("b")
+
+	fsys["b.pem"] = newKeyFile(t)
+	fsys["c.pem"] = newKeyFile(t)
+
+	changed, err := ks.Reload(fsys, time.Hour)
+	if err != nil {
+		t.Fatalf("Should be able to reload: %s", err)
+	}
+	if !slices.Equal(changed, []string{"b"}) {
+		t.Fatalf("Should report the replaced key only: got %v", changed)
+	}
+	if newB, _ := ks.PublicKey("b"); newB == oldB {
+		t.Fatal("Should use the replaced key")
+	}
+	if _, err := ks.PrivateKey("c"); err != nil {
+		t.Fatalf("Should sign with the new key: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A removed file retires the key, it verifies but doesn't sign until the grace period ends.
+
+	delete(fsys, "c.pem")
+
+	changed, err = ks.Reload(fsys, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Should be able to reload: %s", err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("Should not report a key that is only retired: got %v", changed)
+	}
+	if _, err := ks.PrivateKey("c"); !errors.Is(err, keystore.ErrKIDRetired) {
+		t.Fatalf("Should not sign with a retired key: got %v", err)
+	}
+	if _, err := ks.PublicKey("c"); err != nil {
+		t.Fatalf("Should verify with a retired key: %s", err)
+	}
+	if set, _ := ks.JWKS(); len(set.Keys) != 3 {
+		t.Fatalf("Should publish a retired key during the grace period: got %d keys", len(set.Keys))
+	}
+
+	// Reloading within the grace period doesn't restart it and doesn't remove the key.
+	if changed, _ := ks.Reload(fsys, 100*time.Millisecond); len(changed) != 0 {
+		t.Fatalf("Should keep the retired key within the grace period: got %v", changed)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	changed, err = ks.Reload(fsys, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Should be able to reload: %s", err)
+	}
+	if !slices.Equal(changed, []string{"c"}) {
+		t.Fatalf("Should report the key that went away: got %v", changed)
+	}
+	if _, err := ks.PublicKey("c"); !errors.Is(err, keystore.ErrKIDNotFound) {
+		t.Fatalf("Should not verify with a key after its grace period: got %v", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A file coming back before the grace period ends brings the key back.
+
+	file := fsys["b.pem"]
+	delete(fsys, "b.pem")
+	ks.Reload(fsys, time.Hour)
+	fsys["b.pem"] = file
+
+	if _, err := ks.Reload(fsys, time.Hour); err != nil {
+		t.Fatalf("Should be able to reload: %s", err)
+	}
+	if _, err := ks.PrivateKey("b"); err != nil {
+		t.Fatalf("Should sign with a key whose file came back: %s", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// A broken file leaves the store as it was.
+
+	fsys["d.pem"] = &fstest.MapFile{Data: []byte("not a key")}
+
+	if _, err := ks.Reload(fsys, time.Hour); err == nil {
+		t.Fatal("Should fail on a broken file")
+	}
+	if _, err := ks.PrivateKey("b"); err != nil {
+		t.Fatalf("Should keep the keys after a failed reload: %s", err)
+	}
+}
+
+func Test_ReloadKeepsActiveKID(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.pem": newKeyFile(t),
+		"b.pem": newKeyFile(t),
+	}
+
+	ks, err := keystore.NewFS(fsys)
+	if err != nil {
+		t.Fatalf("Should be able to read the keys: %s", err)
+	}
+	if err := ks.SetActiveKID("a"); err != nil {
+		t.Fatalf("Should be able to activate a: %s", err)
+	}
+
+	delete(fsys, "a.pem")
+
+	// The grace period is over right away, the active key still has to stay.
+	for range 2 {
+		if _, err := ks.Reload(fsys, 0); !errors.Is(err, keystore.ErrActiveKIDRemoved) {
+			t.Fatalf("Should report the active kid's file is gone: got %v", err)
+		}
+		if _, err := ks.PrivateKey("a"); err != nil {
+			t.Fatalf("Should still sign with the active key: %s", err)
+		}
+	}
+
+	// Once the operator switches away, the old key is retired like any other.
+	if err := ks.SetActiveKID("b"); err != nil {
+		t.Fatalf("Should be able to switch to b: %s", err)
+	}
+
+	if _, err := ks.Reload(fsys, time.Hour); err != nil {
+		t.Fatalf("Should reload cleanly after switching away: %s", err)
+	}
+	if _, err := ks.PrivateKey("a"); !errors.Is(err, keystore.ErrKIDRetired) {
+		t.Fatalf("Should retire the old active key: got %v", err)
+	}
+}
+
+func Test_Watch(t *testing.T) {
+	var mu sync.Mutex
+	fsys := fstest.MapFS{
+		"a.pem": newKeyFile(t),
+		"b.pem": newKeyFile(t),
+	}
+
+	ks, err := keystore.NewFS(fsys)
+	if err != nil {
+		t.Fatalf("Should be able to read the keys: %s", err)
+	}
+	if err := ks.SetActiveKID("a"); err != nil {
+		t.Fatalf("Should be able to activate a: %s", err)
+	}
+
+	changes := make(chan []string, 10)
+	errs := make(chan error, 10)
+
+	// The watcher reads the directory from its own goroutine, so the test hands it a copy it owns.
+	readFS := func() fstest.MapFS {
+		mu.Lock()
+		defer mu.Unlock()
+		return fstest.MapFS{"a.pem": fsys["a.pem"], "b.pem": fsys["b.pem"]}
+	}
+
+	w := ks.Watch(keystore.WatchConfig{
+		FS:       lockedFS{get: readFS},
+		Interval: 10 * time.Millisecond,
+		Grace:    time.Hour,
+		OnChange: func(kids []string) { changes <- kids },
+		OnError:  func(err error) { errs <- err },
+	})
+	defer w.Close()
+
+	mu.Lock()
+	fsys["b.pem"] = newKeyFile(t)
+	mu.Unlock()
+
+	select {
+	case kids := <-changes:
+		if !slices.Equal(kids, []string{"b"}) {
+			t.Fatalf("Should get the changed kids: got %v", kids)
+		}
+	case err := <-errs:
+		t.Fatalf("Should not fail: %s", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Should be told about the changed key")
+	}
+}
+
+// lockedFS reads through a fresh copy of the directory on every open, so the test can change the files while the
+// watcher is reading them.
+type lockedFS struct {
+	get func() fstest.MapFS
+}
+
+func (l lockedFS) Open(name string) (fs.File, error) {
+	return l.get().Open(name)
+}
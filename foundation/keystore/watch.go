@@ -0,0 +1,82 @@
+package keystore
+
+import (
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// WatchConfig represents the information required to watch a keys directory.
+type WatchConfig struct {
+	FS fs.FS
+	// How often the directory is read again, defaults to 30 seconds. We poll instead of asking the OS for file events,
+	// polling works the same on every platform and on mounted secrets where the files are swapped through symlinks.
+	Interval time.Duration
+	// How long a key whose file is gone is still accepted for verifying tokens. It should be longer than the tokens
+	// signed with it live.
+	Grace time.Duration
+	// OnChange is called with the kids whose public key changed or went away. Optional.
+	OnChange func(kids []string)
+	// OnError is called when reading the directory fails, the store keeps the keys it has, and on every reload while
+	// the file of the active kid is gone. Optional.
+	OnError func(err error)
+}
+
+// Watcher reloads a KeyStore from its directory until it is closed.
+type Watcher struct {
+	ks       *KeyStore
+	cfg      WatchConfig
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Watch starts reloading the store from the directory in the background. Adding a PEM file adds a key, removing one
+// retires it, so keys can be rotated without restarting the service.
+func (ks *KeyStore) Watch(cfg WatchConfig) *Watcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+
+	w := Watcher{
+		ks:       ks,
+		cfg:      cfg,
+		shutdown: make(chan struct{}),
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run()
+	}()
+
+	return &w
+}
+
+// Close stops the watcher.
+func (w *Watcher) Close() {
+	close(w.shutdown)
+	w.wg.Wait()
+}
+
+func (w *Watcher) run() {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// The reload can apply changes and still report an error, e.g. when the file of the active kid is gone.
+			changed, err := w.ks.Reload(w.cfg.FS, w.cfg.Grace)
+			if err != nil && w.cfg.OnError != nil {
+				w.cfg.OnError(err)
+			}
+
+			if len(changed) > 0 && w.cfg.OnChange != nil {
+				w.cfg.OnChange(changed)
+			}
+
+		case <-w.shutdown:
+			return
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package openapi provides support for generating an OpenAPI 3 document from the routes an application has bound and
+// the models the handlers are using.
+// The idea is the document is never written by hand, the routes come from what is really registered on the mux and the
+// schemas come from the same app layer models that we decode and validate with, including their "validate:" tags.
+// So the contract the front-end codes against can't drift away from what the service is doing.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+)
+
+// Version is the OpenAPI specification version this package generates.
+const Version = "3.0.3"
+
+// Param describes a query string parameter an operation accepts.
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Operation describes a single route, the request and response models are values of the app layer models, the
+// document only looks at their type.
+type Operation struct {
+	Summary  string
+	Tag      string
+	Auth     bool
+	Query    []Param
+	Request  any
+	Response any
+	Status   int
+}
+
+// Document collects the operations described by the handler groups and builds the specification on demand.
+type Document struct {
+	title      string
+	version    string
+	errorModel any
+
+	mu  sync.RWMutex
+	ops map[string]Operation
+}
+
+// New constructs a Document. The errorModel is the model every failed call responds with, it gets documented as the
+// default response of all the operations.
+func New(title string, version string, errorModel any) *Document {
+	return &Document{
+		title:      title,
+		version:    version,
+		errorModel: errorModel,
+		ops:        make(map[string]Operation),
+	}
+}
+
+// Describe adds the documentation for the route at the specified method and path. Routes that are never described
+// still show up in the specification, just without models.
+func (d *Document) Describe(method string, path string, op Operation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ops[opKey(method, path)] = op
+}
+
+// Spec builds the specification for the provided routes, they are the routes the App reports as bound so the paths
+// are in the mux format, e.g. "/v1/users/:user_id".
+func (d *Document) Spec(routes []web.Route) Spec {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	sb := newSchemaBuilder()
+
+	spec := Spec{
+		OpenAPI: Version,
+		Info: info{
+			Title:   d.title,
+			Version: d.version,
+		},
+		Paths: make(map[string]map[string]*operation),
+		Components: components{
+			SecuritySchemes: map[string]securityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+				},
+			},
+		},
+	}
+
+	var errSchema *Schema
+	if d.errorModel != nil {
+		errSchema = sb.schemaOf(d.errorModel)
+	}
+
+	for _, rt := range routes {
+		path, pathParams := convertPath(rt.Path)
+
+		op := d.ops[opKey(rt.Method, rt.Path)]
+
+		o := operation{
+			Summary:     op.Summary,
+			OperationID: operationID(rt.Method, rt.Path),
+			Tags:        []string{op.Tag},
+			Responses:   make(map[string]response),
+		}
+		if op.Tag == "" {
+			o.Tags = []string{defaultTag(rt.Path)}
+		}
+
+		for _, name := range pathParams {
+			o.Parameters = append(o.Parameters, parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+
+		for _, q := range op.Query {
+			o.Parameters = append(o.Parameters, parameter{
+				Name:        q.Name,
+				In:          "query",
+				Description: q.Description,
+				Required:    q.Required,
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+
+		if op.Request != nil {
+			o.RequestBody = &requestBody{
+				Required: true,
+				Content:  jsonContent(sb.schemaOf(op.Request)),
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		res := response{Description: http.StatusText(status)}
+		if op.Response != nil && status != http.StatusNoContent {
+			res.Content = jsonContent(sb.schemaOf(op.Response))
+		}
+		o.Responses[fmt.Sprint(status)] = res
+
+		if errSchema != nil {
+			o.Responses["default"] = response{
+				Description: "Error",
+				Content:     jsonContent(errSchema),
+			}
+		}
+
+		if op.Auth {
+			o.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = make(map[string]*operation)
+		}
+		spec.Paths[path][strings.ToLower(rt.Method)] = &o
+	}
+
+	spec.Components.Schemas = sb.schemas
+
+	return spec
+}
+
+// =============================================================================
+
+// Spec represents an OpenAPI 3 document.
+type Spec struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// =============================================================================
+
+func opKey(method string, path string) string {
+	return method + " " + path
+}
+
+func jsonContent(s *Schema) map[string]mediaType {
+	return map[string]mediaType{
+		"application/json": {Schema: s},
+	}
+}
+
+// convertPath converts a mux path like "/v1/users/:user_id" into the OpenAPI form "/v1/users/{user_id}" and returns
+// the names of the path parameters it found.
+func convertPath(path string) (string, []string) {
+	var params []string
+
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			params = append(params, seg[1:])
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+// operationID builds a stable unique id for the operation out of the method and path.
+func operationID(method string, path string) string {
+	f := func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '.' || r == '-'
+	}
+
+	return strings.ToLower(method) + "_" + strings.Join(strings.FieldsFunc(path, f), "_")
+}
+
+// defaultTag groups an operation by the first path segment after the version, e.g. "/v1/users/:user_id" is "users".
+func defaultTag(path string) string {
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+	switch {
+	case len(segments) > 1:
+		return segments[1]
+	case len(segments) == 1:
+		return segments[0]
+	}
+	return "default"
+}
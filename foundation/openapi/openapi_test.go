@@ -0,0 +1,100 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+)
+
+func Test_ConvertPath(t *testing.T) {
+	tt := []struct {
+		path       string
+		want       string
+		wantParams []string
+	}{
+		{path: "/v1/users", want: "/v1/users"},
+		{path: "/v1/users/:user_id", want: "/v1/users/{user_id}", wantParams: []string{"user_id"}},
+		{path: "/v1/users/:user_id/links/:link_id", want: "/v1/users/{user_id}/links/{link_id}", wantParams: []string{"user_id", "link_id"}},
+		{path: "/v1/files/*filepath", want: "/v1/files/{filepath}", wantParams: []string{"filepath"}},
+		{path: "/v1/users/:user_id/", want: "/v1/users/{user_id}/", wantParams: []string{"user_id"}},
+		{path: "/v1/a:b", want: "/v1/a:b"},
+		{path: "/", want: "/"},
+	}
+
+	for _, tst := range tt {
+		got, params := convertPath(tst.path)
+		if got != tst.want {
+			t.Errorf("Should convert %q: got %q, want %q", tst.path, got, tst.want)
+		}
+		if !reflect.DeepEqual(params, tst.wantParams) {
+			t.Errorf("Should find the params of %q: got %v, want %v", tst.path, params, tst.wantParams)
+		}
+	}
+}
+
+func Test_Spec(t *testing.T) {
+	type appUser struct {
+		Name string `json:"name"`
+	}
+	type appNewUser struct {
+		Name string `json:"name" validate:"required"`
+	}
+	type appError struct {
+		Error string `json:"error"`
+	}
+
+	doc := New("test", "1.0", appError{})
+	doc.Describe(http.MethodGet, "/v1/users/:user_id", Operation{Summary: "Query a user", Tag: "users", Auth: true, Response: appUser{}})
+	doc.Describe(http.MethodPost, "/v1/users", Operation{Request: appNewUser{}, Response: appUser{}, Status: http.StatusCreated})
+	doc.Describe(http.MethodDelete, "/v1/users/:user_id", Operation{Response: appUser{}, Status: http.StatusNoContent})
+
+	spec := doc.Spec([]web.Route{
+		{Method: http.MethodGet, Path: "/v1/users/:user_id"},
+		{Method: http.MethodPost, Path: "/v1/users"},
+		{Method: http.MethodDelete, Path: "/v1/users/:user_id"},
+		{Method: http.MethodGet, Path: "/v1/readiness"},
+	})
+
+	get := spec.Paths["/v1/users/{user_id}"]["get"]
+	if get == nil {
+		t.Fatalf("Should document the route under the OpenAPI path, got %v", spec.Paths)
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "user_id" || get.Parameters[0].In != "path" {
+		t.Errorf("Should document the path parameter, got %+v", get.Parameters)
+	}
+	if get.Security == nil {
+		t.Error("Should require the bearer token on an authenticated route")
+	}
+	if got := get.Responses["200"].Content["application/json"].Schema.Ref; got != "#/components/schemas/appUser" {
+		t.Errorf("Should reference the response model, got %q", got)
+	}
+	if got := get.Responses["default"].Content["application/json"].Schema.Ref; got != "#/components/schemas/appError" {
+		t.Errorf("Should document the error model as the default response, got %q", got)
+	}
+
+	post := spec.Paths["/v1/users"]["post"]
+	if post == nil || post.RequestBody == nil {
+		t.Fatal("Should document the request body of the post")
+	}
+	if _, exists := post.Responses["201"]; !exists {
+		t.Errorf("Should document the status of the operation, got %v", post.Responses)
+	}
+	if got := post.Tags; !reflect.DeepEqual(got, []string{"users"}) {
+		t.Errorf("Should tag an operation without a tag by its path, got %v", got)
+	}
+
+	del := spec.Paths["/v1/users/{user_id}"]["delete"]
+	if del == nil || del.Responses["204"].Content != nil {
+		t.Error("Should not document a body for a 204")
+	}
+
+	if spec.Paths["/v1/readiness"]["get"] == nil {
+		t.Error("Should still document a route nobody described")
+	}
+
+	if ids := []string{get.OperationID, post.OperationID, del.OperationID}; ids[0] == ids[2] {
+		t.Errorf("Should give every operation a unique id, got %v", ids)
+	}
+}
@@ -0,0 +1,277 @@
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema represents an OpenAPI schema object, only the parts we can derive from a Go type and its tags.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaBuilder walks Go types with reflection and collects every named struct as a component, so a model used by
+// many operations is only described once.
+type schemaBuilder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema for the type of the provided value.
+func (sb *schemaBuilder) schemaOf(v any) *Schema {
+	return sb.schemaFor(reflect.TypeOf(v))
+}
+
+func (sb *schemaBuilder) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := sb.schemaFor(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	}
+
+	// Types that know how to marshal themselves into text are strings on the wire, no matter what they are in Go.
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: sb.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sb.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return sb.structSchema(t)
+	}
+
+	// Interfaces and anything else can hold any value.
+	return &Schema{}
+}
+
+// structSchema returns a reference to the component for a named struct, or the schema itself for an anonymous one.
+func (sb *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return sb.objectSchema(t)
+	}
+
+	if name, exists := sb.names[t]; exists {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := sb.componentName(t)
+
+	// Register the name before walking the fields so a type that refers to itself doesn't loop forever.
+	sb.names[t] = name
+	sb.schemas[name] = sb.objectSchema(t)
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (sb *schemaBuilder) objectSchema(t reflect.Type) *Schema {
+	s := Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	sb.addFields(&s, t)
+
+	return &s
+}
+
+// addFields adds the exported fields of the struct following the same rules the JSON package uses for naming them.
+func (sb *schemaBuilder) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// Embedded structs without a name get their fields promoted, just like in JSON.
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				sb.addFields(s, ft)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fs := sb.schemaFor(field.Type)
+		if strings.Contains(opts, "string") {
+			fs = &Schema{Type: "string"}
+		}
+
+		if applyValidate(fs, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+
+		s.Properties[name] = fs
+	}
+}
+
+// componentName builds a readable name for the component. Generic types are named after their type arguments, so
+// "PageDocument[x/usergrp.AppUser]" becomes "PageDocumentAppUser". Two types with the same name in different packages
+// get the package name as a prefix.
+func (sb *schemaBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if base, args, found := strings.Cut(name, "["); found {
+		name = base
+		for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+			name += arg[strings.LastIndex(arg, ".")+1:]
+		}
+	}
+
+	if _, exists := sb.schemas[name]; exists {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + name
+	}
+
+	return name
+}
+
+// =============================================================================
+
+// applyValidate translates the "validate:" tags of a field into the schema and reports if the field is required.
+// Only the tags that have a meaning in OpenAPI are translated, everything else is still enforced by the service.
+func applyValidate(s *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	var required bool
+
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		key, value, _ := strings.Cut(rule, "=")
+
+		switch key {
+		case "required":
+			required = true
+
+		case "dive":
+			// Everything after dive applies to the items of the slice or map.
+			target := s.Items
+			if target == nil {
+				target = s.AdditionalProperties
+			}
+			if target != nil {
+				applyValidate(target, strings.Join(rules[i+1:], ","))
+			}
+			return required
+
+		case "email":
+			s.Format = "email"
+
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+
+		case "url", "uri", "http_url":
+			s.Format = "uri"
+
+		case "oneof":
+			s.Enum = strings.Fields(value)
+
+		case "min", "gte":
+			setBound(s, value, true)
+
+		case "max", "lte":
+			setBound(s, value, false)
+
+		case "len":
+			setBound(s, value, true)
+			setBound(s, value, false)
+		}
+	}
+
+	return required
+}
+
+// setBound sets the lower or upper bound of the schema, the meaning of a bound depends on the type of the field.
+func setBound(s *Schema, value string, lower bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	switch s.Type {
+	case "string":
+		if lower {
+			s.MinLength = &i
+			return
+		}
+		s.MaxLength = &i
+
+	case "array":
+		if lower {
+			s.MinItems = &i
+			return
+		}
+		s.MaxItems = &i
+
+	case "integer", "number":
+		if lower {
+			s.Minimum = &n
+			return
+		}
+		s.Maximum = &n
+	}
+}
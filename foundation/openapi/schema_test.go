@@ -0,0 +1,199 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type person struct {
+	Name     string            `json:"name"`
+	Nickname *string           `json:"nickname"`
+	Age      int               `json:"age"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]int64  `json:"labels"`
+	Born     time.Time         `json:"born"`
+	ID       uuid.UUID         `json:"id"`
+	Home     address           `json:"home"`
+	Work     *address          `json:"work"`
+	Inline   struct{ X bool }  `json:"inline"`
+	Raw      []byte            `json:"raw"`
+	Any      any               `json:"any"`
+	Skipped  string            `json:"-"`
+	Count    int               `json:"count,string"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	hidden   string
+}
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+// URL has the same name as url.URL, the second one registered gets its package name as a prefix.
+type URL struct {
+	Short string `json:"short"`
+}
+
+func Test_SchemaFor(t *testing.T) {
+	sb := newSchemaBuilder()
+
+	ref := sb.schemaOf(person{})
+	if want := "#/components/schemas/person"; ref.Ref != want {
+		t.Fatalf("Should reference the named struct as a component: got %q, want %q", ref.Ref, want)
+	}
+
+	props := sb.schemas["person"].Properties
+
+	tt := []struct {
+		field string
+		want  *Schema
+	}{
+		{field: "name", want: &Schema{Type: "string"}},
+		{field: "nickname", want: &Schema{Type: "string", Nullable: true}},
+		{field: "age", want: &Schema{Type: "integer", Format: "int32"}},
+		{field: "tags", want: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+		{field: "labels", want: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int64"}}},
+		{field: "born", want: &Schema{Type: "string", Format: "date-time"}},
+		{field: "id", want: &Schema{Type: "string"}},
+		{field: "home", want: &Schema{Ref: "#/components/schemas/address"}},
+		{field: "work", want: &Schema{Ref: "#/components/schemas/address"}},
+		{field: "inline", want: &Schema{Type: "object", Properties: map[string]*Schema{"X": {Type: "boolean"}}}},
+		{field: "raw", want: &Schema{Type: "string", Format: "byte"}},
+		{field: "any", want: &Schema{}},
+		{field: "count", want: &Schema{Type: "string"}},
+	}
+
+	for _, tst := range tt {
+		got := props[tst.field]
+		if !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("Should describe %s: got %s, want %s", tst.field, toJSON(got), toJSON(tst.want))
+		}
+	}
+
+	for _, name := range []string{"Skipped", "hidden"} {
+		if _, exists := props[name]; exists {
+			t.Errorf("Should leave %s out of the schema", name)
+		}
+	}
+
+	if _, exists := sb.schemas["address"]; !exists {
+		t.Error("Should register the nested struct as a component")
+	}
+}
+
+func Test_ComponentNames(t *testing.T) {
+	sb := newSchemaBuilder()
+
+	tt := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "generic", value: page[person]{}, want: "#/components/schemas/pageperson"},
+		{name: "first URL", value: URL{}, want: "#/components/schemas/URL"},
+		{name: "same name, other package", value: url.URL{}, want: "#/components/schemas/urlURL"},
+		{name: "same type again", value: URL{}, want: "#/components/schemas/URL"},
+	}
+
+	for _, tst := range tt {
+		if got := sb.schemaOf(tst.value).Ref; got != tst.want {
+			t.Errorf("%s: Should reference %q, got %q", tst.name, tst.want, got)
+		}
+	}
+
+	if short := sb.schemas["URL"].Properties["short"]; short == nil {
+		t.Error("Should keep the first URL under the plain name")
+	}
+	if host := sb.schemas["urlURL"].Properties["Host"]; host == nil {
+		t.Error("Should describe url.URL under the prefixed name")
+	}
+}
+
+func Test_ApplyValidate(t *testing.T) {
+	tt := []struct {
+		name         string
+		schema       *Schema
+		tag          string
+		want         *Schema
+		wantRequired bool
+	}{
+		{name: "empty", schema: &Schema{Type: "string"}, tag: "", want: &Schema{Type: "string"}},
+		{name: "required", schema: &Schema{Type: "string"}, tag: "required", want: &Schema{Type: "string"}, wantRequired: true},
+		{name: "omitempty", schema: &Schema{Type: "string"}, tag: "omitempty,min=2", want: &Schema{Type: "string", MinLength: ptr(2)}},
+		{name: "string bounds", schema: &Schema{Type: "string"}, tag: "min=2,max=10", want: &Schema{Type: "string", MinLength: ptr(2), MaxLength: ptr(10)}},
+		{name: "number bounds", schema: &Schema{Type: "integer"}, tag: "gte=1,lte=100", want: &Schema{Type: "integer", Minimum: ptr(1.0), Maximum: ptr(100.0)}},
+		{name: "array bounds", schema: &Schema{Type: "array"}, tag: "min=1", want: &Schema{Type: "array", MinItems: ptr(1)}},
+		{name: "len", schema: &Schema{Type: "string"}, tag: "len=4", want: &Schema{Type: "string", MinLength: ptr(4), MaxLength: ptr(4)}},
+		{name: "bad bound", schema: &Schema{Type: "string"}, tag: "min=abc", want: &Schema{Type: "string"}},
+		{name: "oneof", schema: &Schema{Type: "string"}, tag: "required,oneof=ADMIN USER", want: &Schema{Type: "string", Enum: []string{"ADMIN", "USER"}}, wantRequired: true},
+		{name: "email", schema: &Schema{Type: "string"}, tag: "required,email", want: &Schema{Type: "string", Format: "email"}, wantRequired: true},
+		{name: "uuid", schema: &Schema{Type: "string"}, tag: "uuid4", want: &Schema{Type: "string", Format: "uuid"}},
+		{name: "url", schema: &Schema{Type: "string"}, tag: "url", want: &Schema{Type: "string", Format: "uri"}},
+		{name: "unknown tag", schema: &Schema{Type: "string"}, tag: "eqfield=Password", want: &Schema{Type: "string"}},
+		{
+			name:         "dive into a slice",
+			schema:       &Schema{Type: "array", Items: &Schema{Type: "string"}},
+			tag:          "required,max=3,dive,oneof=a b",
+			want:         &Schema{Type: "array", MaxItems: ptr(3), Items: &Schema{Type: "string", Enum: []string{"a", "b"}}},
+			wantRequired: true,
+		},
+		{
+			name:   "dive into a map",
+			schema: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			tag:    "dive,email",
+			want:   &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string", Format: "email"}},
+		},
+		{
+			name:   "required after dive is about the items",
+			schema: &Schema{Type: "array", Items: &Schema{Type: "string"}},
+			tag:    "dive,required",
+			want:   &Schema{Type: "array", Items: &Schema{Type: "string"}},
+		},
+	}
+
+	for _, tst := range tt {
+		required := applyValidate(tst.schema, tst.tag)
+
+		if required != tst.wantRequired {
+			t.Errorf("%s: Should report required %t, got %t", tst.name, tst.wantRequired, required)
+		}
+		if !reflect.DeepEqual(tst.schema, tst.want) {
+			t.Errorf("%s: Should translate %q: got %s, want %s", tst.name, tst.tag, toJSON(tst.schema), toJSON(tst.want))
+		}
+	}
+}
+
+func Test_RequiredFields(t *testing.T) {
+	type login struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required,min=8"`
+		Remember bool   `json:"remember"`
+	}
+
+	sb := newSchemaBuilder()
+	sb.schemaOf(login{})
+
+	s := sb.schemas["login"]
+	if want := []string{"email", "password"}; !reflect.DeepEqual(s.Required, want) {
+		t.Errorf("Should list the required fields by their JSON name: got %v, want %v", s.Required, want)
+	}
+}
+
+// =============================================================================
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func toJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
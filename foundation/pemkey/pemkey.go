@@ -0,0 +1,84 @@
+// Package pemkey parses the PEM encoded keys tokens are signed and verified with. The kind of key is detected from the
+// type of the PEM block, so the keystore, auth and the token tool all read the same files the same way.
+package pemkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ParsePrivate parses a private key. The result is an *rsa.PrivateKey, an *ecdsa.PrivateKey or an ed25519.PrivateKey,
+// they all are a crypto.Signer which is what gives us the public half.
+func ParsePrivate(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			// Older versions of the token tool wrote PKCS1 RSA keys with this type.
+			if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+				return rsaKey, nil
+			}
+			return nil, err
+		}
+
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return key, nil
+		case *ecdsa.PrivateKey:
+			return key, nil
+		case ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+}
+
+// ParsePublic parses a public key. The result is an *rsa.PublicKey, an *ecdsa.PublicKey or an ed25519.PublicKey.
+func ParsePublic(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			return key, nil
+		case *ecdsa.PublicKey:
+			return key, nil
+		case ed25519.PublicKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+}
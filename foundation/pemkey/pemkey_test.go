@@ -0,0 +1,86 @@
+package pemkey_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/pemkey"
+)
+
+func Test_Parse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate an rsa key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate an ec key: %s", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate an ed25519 key: %s", err)
+	}
+
+	pkcs8 := func(key crypto.Signer) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Should be able to marshal the key: %s", err)
+		}
+		return der
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the ec key: %s", err)
+	}
+
+	tt := []struct {
+		name  string
+		block pem.Block
+		key   crypto.Signer
+	}{
+		{name: "rsa pkcs1", block: pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, key: rsaKey},
+		{name: "rsa pkcs8", block: pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(rsaKey)}, key: rsaKey},
+		{name: "rsa pkcs1 legacy type", block: pem.Block{Type: "PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, key: rsaKey},
+		{name: "ec", block: pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}, key: ecKey},
+		{name: "ec pkcs8", block: pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(ecKey)}, key: ecKey},
+		{name: "ed25519", block: pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(edKey)}, key: edKey},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			got, err := pemkey.ParsePrivate(pem.EncodeToMemory(&tst.block))
+			if err != nil {
+				t.Fatalf("Should be able to parse the private key: %s", err)
+			}
+			if !tst.key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(got.Public()) {
+				t.Fatal("Should get the same private key back")
+			}
+
+			der, err := x509.MarshalPKIXPublicKey(got.Public())
+			if err != nil {
+				t.Fatalf("Should be able to marshal the public key: %s", err)
+			}
+			pub, err := pemkey.ParsePublic(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			if err != nil {
+				t.Fatalf("Should be able to parse the public key: %s", err)
+			}
+			if !tst.key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(pub) {
+				t.Fatal("Should get the same public key back")
+			}
+		})
+	}
+
+	if _, err := pemkey.ParsePrivate([]byte("not a key")); err == nil {
+		t.Fatal("Should fail without a PEM block")
+	}
+	if _, err := pemkey.ParsePrivate(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})); err == nil {
+		t.Fatal("Should fail on a PEM type that is not a key")
+	}
+}
@@ -18,8 +18,8 @@ type validator interface {
 func Param(r *http.Request, key string) string {
 	// r.Context().Value(paramKey), fetches the value stored under paramKey in the request’s context.
 	// Earlier, we injected Gin’s c.Params slice via middleware.
-	// Then we are doing ".([]gin.Param)"
-	if ps, _ := r.Context().Value(paramKey).([]gin.Param); len(ps) > 0 {
+	// Then we are doing ".(gin.Params)", it has to be the named type that was stored, asserting to "[]gin.Param" fails.
+	if ps, _ := r.Context().Value(paramKey).(gin.Params); len(ps) > 0 {
 		for _, p := range ps {
 			if p.Key == key {
 				return p.Value
@@ -36,3 +36,18 @@ func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode in
 
 	return nil
 }
+
+// RespondRaw sends bytes that are already encoded to the client with the content type given, e.g. an HTML page.
+// Like Respond it records the status code, so the logger and metrics see it.
+func RespondRaw(ctx context.Context, w http.ResponseWriter, data []byte, contentType string, statusCode int) error {
+	setStatusCode(ctx, statusCode)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Respond is JSON only, it writes the whole document and it is done. Streaming is different, the handler keeps the
+// connection open and pushes data as it happens, so every write has to be flushed right away or it sits in a buffer.
+// These functions give handlers that ability for Server-Sent Events without the handler knowing about the mux.
+
+// StreamStart prepares the response for a stream of Server-Sent Events and sends the headers to the client.
+// The server WriteTimeout is meant for normal requests, a stream would be killed by it, so we clear the write deadline
+// for this connection only.
+func StreamStart(ctx context.Context, w http.ResponseWriter, statusCode int) error {
+	setStatusCode(ctx, statusCode)
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("clearing write deadline: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(statusCode)
+
+	if err := rc.Flush(); err != nil {
+		return fmt.Errorf("flushing headers: %w", err)
+	}
+
+	return nil
+}
+
+// StreamEvent converts a Go value to JSON and sends it to the client as a single Server-Sent Event.
+// The event name is optional, when it is empty the client receives it as a default "message" event.
+func StreamEvent(w http.ResponseWriter, event string, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", jsonData); err != nil {
+		return err
+	}
+
+	return http.NewResponseController(w).Flush()
+}
+
+// StreamComment sends a comment line to the client. Clients ignore comments, this is used as a heartbeat so proxies
+// don't close a connection that is quiet for a while.
+func StreamComment(w http.ResponseWriter, comment string) error {
+	if _, err := fmt.Fprintf(w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+
+	return http.NewResponseController(w).Flush()
+}
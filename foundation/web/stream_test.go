@@ -0,0 +1,98 @@
+package web_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MinaMamdouh2/URL-Shortener/foundation/web"
+	"github.com/gin-gonic/gin"
+)
+
+func Test_Stream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The handler doesn't return until the client has read the first event, so the test only passes when every
+	// write is flushed to the client and not held in a buffer until the handler is done.
+	read := make(chan struct{})
+
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if err := web.StreamStart(ctx, w, http.StatusOK); err != nil {
+			return err
+		}
+		if err := web.StreamEvent(w, "click", map[string]int{"count": 1}); err != nil {
+			return err
+		}
+
+		select {
+		case <-read:
+		case <-time.After(5 * time.Second):
+		}
+
+		if err := web.StreamComment(w, "ping"); err != nil {
+			return err
+		}
+		return web.StreamEvent(w, "", "done")
+	}
+
+	app := web.NewApp(make(chan os.Signal, 1))
+	app.Handle(http.MethodGet, "", "/stream", h)
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatalf("Should be able to open the stream: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Should get a 200: got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Should get the event stream content type: got %q", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Fatalf("Should get no-cache: got %q", cc)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	// An event is the lines up to the next empty line.
+	readEvent := func() string {
+		var lines []string
+		for sc.Scan() {
+			if sc.Text() == "" {
+				break
+			}
+			lines = append(lines, sc.Text())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	first := make(chan string, 1)
+	go func() { first <- readEvent() }()
+
+	select {
+	case got := <-first:
+		want := "event: click\ndata: {\"count\":1}"
+		if got != want {
+			t.Fatalf("Should get the framed event:\ngot  %q\nwant %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Should get the first event before the handler returns, it was not flushed")
+	}
+	close(read)
+
+	if got := readEvent(); got != ": ping" {
+		t.Fatalf("Should get the comment: got %q", got)
+	}
+	if got := readEvent(); got != `data: "done"` {
+		t.Fatalf("Should get the default event: got %q", got)
+	}
+}
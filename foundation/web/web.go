@@ -27,6 +27,15 @@ type App struct {
 	shutdown chan os.Signal
 	// We are gonna add a slice of middleware functions
 	mw []Middleware
+	// We keep track of every route that gets bound, so things like API documentation can be generated from what is
+	// really registered instead of a hand maintained list.
+	routes []Route
+}
+
+// Route describes a method and path pair that has been bound to the App.
+type Route struct {
+	Method string
+	Path   string
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
@@ -117,6 +126,15 @@ func (a *App) handle(method string, group string, path string, handler Handler)
 	// We can create all the abstraction in the world but at the end of the day what is implementing the mux is the
 	// the context mux
 	a.Engine.Handle(method, finalPath, h)
+	a.routes = append(a.routes, Route{Method: method, Path: finalPath})
+}
+
+// RegisteredRoutes returns a copy of the routes bound to the App in the order they were registered.
+// It is not called Routes so it doesn't hide the Routes method gin promotes up to App.
+func (a *App) RegisteredRoutes() []Route {
+	routes := make([]Route, len(a.routes))
+	copy(routes, a.routes)
+	return routes
 }
 
 // SignalShutdown is used to gracefully shut down the app when an integrity issue is identified.